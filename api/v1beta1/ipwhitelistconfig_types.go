@@ -17,6 +17,10 @@ limitations under the License.
 package v1beta1
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -31,6 +35,12 @@ type AkamaiProvider struct {
 	// +kubebuilder:validation:XIntOrString
 	// +kubebuilder:validation:Optional
 	MapId *intstr.IntOrString `json:"mapId,omitempty"`
+	// MapIds are more Site Shield maps to whitelist along with mapId
+	// +kubebuilder:validation:Optional
+	MapIds []intstr.IntOrString `json:"mapIds,omitempty"`
+	// AllMaps whitelists all the Site Shield maps visible to the credentials, mapId and mapIds are ignored
+	// +kubebuilder:validation:Optional
+	AllMaps bool `json:"allMaps,omitempty"`
 	// +kubebuilder:validation:Optional
 	Host *SecretKeySelector `json:"serviceConsumerDomainRef,omitempty"`
 	// +kubebuilder:validation:Optional
@@ -39,13 +49,63 @@ type AkamaiProvider struct {
 	ClientSecret *SecretKeySelector `json:"clientSecretSecretRef,omitempty"`
 	// +kubebuilder:validation:Optional
 	AccessToken *SecretKeySelector `json:"accessTokenSecretRef,omitempty"`
+	// EdgercSecretRef is a Secret key holding an .edgerc file with the credentials, used instead of the four SecretRefs
+	// +kubebuilder:validation:Optional
+	EdgercSecretRef *SecretKeySelector `json:"edgercSecretRef,omitempty"`
+	// EdgercPath is the path of an .edgerc file with the credentials mounted into the pod of the operator
+	// +kubebuilder:validation:Optional
+	EdgercPath string `json:"edgercPath,omitempty"`
+	// EdgercSection is the section of the .edgerc file with the credentials, also the section of the credentials read
+	// from the environment
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=default
+	EdgercSection string `json:"edgercSection,omitempty"`
+	// CredentialsFromEnv reads the credentials from the AKAMAI_HOST, AKAMAI_CLIENT_TOKEN, AKAMAI_CLIENT_SECRET and
+	// AKAMAI_ACCESS_TOKEN environment variables of the operator, AKAMAI_<SECTION>_HOST and so on for another section
+	// than default
+	// +kubebuilder:validation:Optional
+	CredentialsFromEnv bool `json:"credentialsFromEnv,omitempty"`
+	// Acknowledge decides who acknowledges the proposed cidrs of the map
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Manual
+	Acknowledge SiteShieldAcknowledge `json:"acknowledge,omitempty"`
+	// Transition decides which cidrs of the map are whitelisted while it has proposed cidrs
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Proposed
+	Transition SiteShieldTransition `json:"transition,omitempty"`
 }
 
+// SiteShieldAcknowledge decides who acknowledges the proposed cidrs of a Site Shield map
+// +kubebuilder:validation:Enum=Manual;Auto;AfterApplied
+type SiteShieldAcknowledge string
+
+const (
+	// SiteShieldAcknowledgeManual leaves acknowledging to you, the pending proposal is reported in the status
+	SiteShieldAcknowledgeManual SiteShieldAcknowledge = "Manual"
+	// SiteShieldAcknowledgeAuto acknowledges a proposal as soon as it is fetched
+	SiteShieldAcknowledgeAuto SiteShieldAcknowledge = "Auto"
+	// SiteShieldAcknowledgeAfterApplied acknowledges a proposal once every ingress using the provider whitelists the
+	// proposed cidrs
+	SiteShieldAcknowledgeAfterApplied SiteShieldAcknowledge = "AfterApplied"
+)
+
+// SiteShieldTransition decides which cidrs of a Site Shield map are whitelisted while it has proposed cidrs
+// +kubebuilder:validation:Enum=Proposed;Union
+type SiteShieldTransition string
+
+const (
+	// SiteShieldTransitionProposed whitelists only the proposed cidrs as soon as there are any
+	SiteShieldTransitionProposed SiteShieldTransition = "Proposed"
+	// SiteShieldTransitionUnion whitelists both the current and the proposed cidrs until Akamai has moved the map to
+	// the proposed ones, as traffic keeps coming from the current cidrs while it does
+	SiteShieldTransitionUnion SiteShieldTransition = "Union"
+)
+
 type Providers struct {
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=akamai;cloudflare;fastly;github
+	// +kubebuilder:validation:Enum=akamai;aws;azure;cloudflare;fastly;gcp;github
 	Type ProviderName `json:"type"`
 	// +kubebuilder:validation:Optional
 	Akamai AkamaiProvider `json:"akamai,omitempty"`
@@ -55,8 +115,121 @@ type Providers struct {
 	Fastly FastlyProvider `json:"fastly,omitempty"`
 	// +kubebuilder:validation:Optional
 	Github GithubProvider `json:"github,omitempty"`
+	// +kubebuilder:validation:Optional
+	AWS AWSProvider `json:"aws,omitempty"`
+	// +kubebuilder:validation:Optional
+	GCP GCPProvider `json:"gcp,omitempty"`
+	// +kubebuilder:validation:Optional
+	Azure *AzureProvider `json:"azure,omitempty"`
+	// FailurePolicy decides what happens to the rules using the provider when fetching its cidrs fails
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Fail
+	FailurePolicy ProviderFailurePolicy `json:"failurePolicy,omitempty"`
+	// Mode decides where the cidrs of the provider come from
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Fetch
+	Mode ProviderMode `json:"mode,omitempty"`
+	// MaxShrinkPercent is how much smaller, in percent of the number of cidrs, a fetch may be than the last known good
+	// one. A fetch shrinking more is held back until it is acknowledged, no limit if not set.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxShrinkPercent *int32 `json:"maxShrinkPercent,omitempty"`
+	// MinEntries is the fewest cidrs a fetch may return. A fetch with fewer is held back until it is acknowledged.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	MinEntries *int32 `json:"minEntries,omitempty"`
+	// HTTPClient configures the http client used to fetch the provider
+	// +kubebuilder:validation:Optional
+	HTTPClient *ProviderHTTPClient `json:"httpClient,omitempty"`
+}
+
+// ProviderHTTPClient configures the http client used to fetch a provider
+type ProviderHTTPClient struct {
+	// Timeout of a fetch of the provider, retries included
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="30s"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// ProxyURL is the proxy the provider is reached through, the HTTPS_PROXY and NO_PROXY of the operator are used if
+	// not set
+	// +kubebuilder:validation:Optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+	// CABundle is the PEM encoded CAs trusted for the provider, in addition to the ones of the system
+	// +kubebuilder:validation:Optional
+	CABundle *CABundleSource `json:"caBundle,omitempty"`
+	// ClientCertificate is a kubernetes.io/tls Secret with the client certificate presented to the provider
+	// +kubebuilder:validation:Optional
+	ClientCertificate *v1.SecretReference `json:"clientCertificateSecretRef,omitempty"`
+	// Retry retries failed requests to the provider, requests are not retried if not set
+	// +kubebuilder:validation:Optional
+	Retry *ProviderRetry `json:"retry,omitempty"`
+}
+
+// CABundleSource is where a CA bundle is read from, either a Secret or a ConfigMap
+type CABundleSource struct {
+	// +kubebuilder:validation:Optional
+	SecretRef *SecretKeySelector `json:"secretRef,omitempty"`
+	// +kubebuilder:validation:Optional
+	ConfigMapRef *ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+type ConfigMapKeySelector struct {
+	ConfigMap ConfigMapReference `json:"configMap"`
+	Key       string             `json:"key"`
+}
+
+// ConfigMapReference is the name and namespace of a ConfigMap, like a SecretReference
+type ConfigMapReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// ProviderRetry retries the requests to a provider failing with a network error, a 5xx, a 429 or a 408, waiting for
+// the Retry-After of the response if it is longer than the backoff
+type ProviderRetry struct {
+	// MaxRetries is how many times a request is retried
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	MaxRetries int32 `json:"maxRetries,omitempty"`
+	// Backoff is the wait before the first retry, doubled for every following one
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="1s"
+	Backoff metav1.Duration `json:"backoff,omitempty"`
 }
 
+// AcknowledgeProviderAnnotationPrefix is the prefix of the annotation on the IPWhitelistConfig acknowledging a held
+// back fetch of a provider, followed by the name of the provider, with the hash of the fetched cidrs as value
+const AcknowledgeProviderAnnotationPrefix = "acknowledge.ingress.security.moulick/"
+
+// ProviderMode decides where the cidrs of a provider come from
+// +kubebuilder:validation:Enum=Fetch;SnapshotOnly
+type ProviderMode string
+
+const (
+	// ProviderModeFetch fetches the cidrs from the provider, and saves them in the ProviderSnapshot of the same name
+	ProviderModeFetch ProviderMode = "Fetch"
+	// ProviderModeSnapshotOnly never reaches out to the provider and only uses the ProviderSnapshot of the same name,
+	// like in air-gapped clusters
+	ProviderModeSnapshotOnly ProviderMode = "SnapshotOnly"
+)
+
+// ProviderFailurePolicy decides what happens when fetching the cidrs of a provider fails
+// +kubebuilder:validation:Enum=Fail;UseLastKnownGood;Skip
+type ProviderFailurePolicy string
+
+const (
+	// FailurePolicyFail stops the reconcile of the ingress and retries, the ingress keeps its current whitelist
+	FailurePolicyFail ProviderFailurePolicy = "Fail"
+	// FailurePolicyUseLastKnownGood uses the cidrs of the last successful fetch, or of the ProviderSnapshot, and fails
+	// if there is neither
+	FailurePolicyUseLastKnownGood ProviderFailurePolicy = "UseLastKnownGood"
+	// FailurePolicySkip leaves the provider out of the whitelist, with a warning
+	FailurePolicySkip ProviderFailurePolicy = "Skip"
+)
+
 type ProviderName string
 
 const (
@@ -64,6 +237,9 @@ const (
 	Akamai     ProviderName = "akamai"
 	Fastly     ProviderName = "fastly"
 	Github     ProviderName = "github"
+	AWS        ProviderName = "aws"
+	GCP        ProviderName = "gcp"
+	Azure      ProviderName = "azure"
 )
 
 type CloudflareProvider struct {
@@ -78,6 +254,17 @@ type FastlyProvider struct {
 	JsonApi string `json:"jsonApi"`
 }
 
+// GithubFlavor is the kind of GitHub serving the meta API
+// +kubebuilder:validation:Enum=dotcom;enterprise
+type GithubFlavor string
+
+const (
+	// GithubFlavorDotcom is github.com
+	GithubFlavorDotcom GithubFlavor = "dotcom"
+	// GithubFlavorEnterprise is GitHub Enterprise Server, with its meta API at /api/v3/meta
+	GithubFlavorEnterprise GithubFlavor = "enterprise"
+)
+
 // GithubProvider is a provider for the GitHub meta API
 // +kubebuilder:validation:Optional
 type GithubProvider struct {
@@ -85,30 +272,260 @@ type GithubProvider struct {
 	// +kubebuilder:default="https://api.github.com/meta"
 	JsonApi string `json:"jsonApi,omitempty"`
 
-	// Services are names of sections with IP addresses in the api.github.com/meta like "hooks", "web", "api", "actions" etc
-	// +kubebuilder:validation:Required
+	// Flavor is the kind of GitHub serving the jsonApi. The jsonApi of GitHub Enterprise Server has to be set, like
+	// https://github.example.com/api/v3/meta
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=dotcom
+	Flavor GithubFlavor `json:"flavor,omitempty"`
+
+	// Services are names of sections with IP addresses in the api.github.com/meta like "hooks", "web", "api", "actions"
+	// etc, all the services if not set
+	// +kubebuilder:validation:Optional
 	Services []string `json:"services,omitempty"`
 
 	// +kubebuilder:default="2022-11-28"
 	// +kubebuilder:validation:Optional
 	APIVersion string `json:"apiVersion,omitempty"`
+
+	// TokenSecretRef is a GitHub token sent with the requests, for the higher rate limit of authenticated requests
+	// +kubebuilder:validation:Optional
+	TokenSecretRef *SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+// AWSProvider is a provider for the ip-ranges.json of AWS. A prefix is whitelisted if it matches all of services,
+// regions and networkBorderGroups.
+type AWSProvider struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="https://ip-ranges.amazonaws.com/ip-ranges.json"
+	JsonApi string `json:"jsonApi,omitempty"`
+	// Services of the prefixes like CLOUDFRONT_ORIGIN_FACING or ROUTE53_HEALTHCHECKS, all the services if not set
+	// +kubebuilder:validation:Optional
+	Services []string `json:"services,omitempty"`
+	// Regions of the prefixes like eu-west-1 or GLOBAL, all the regions if not set
+	// +kubebuilder:validation:Optional
+	Regions []string `json:"regions,omitempty"`
+	// NetworkBorderGroups of the prefixes like us-west-2 or us-west-2-lax-1, all the network border groups if not set
+	// +kubebuilder:validation:Optional
+	NetworkBorderGroups []string `json:"networkBorderGroups,omitempty"`
+}
+
+// GCPProvider is a provider for the ip ranges published by Google, either cloud.json with the ranges of Google Cloud
+// or goog.json with the ranges of the Google services. A prefix is whitelisted if it matches both scopes and services.
+type GCPProvider struct {
+	// JsonApi is https://www.gstatic.com/ipranges/cloud.json or https://www.gstatic.com/ipranges/goog.json
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="https://www.gstatic.com/ipranges/cloud.json"
+	JsonApi string `json:"jsonApi,omitempty"`
+	// Scopes of the prefixes of cloud.json like us-central1 or europe-west1, all the scopes if not set
+	// +kubebuilder:validation:Optional
+	Scopes []string `json:"scopes,omitempty"`
+	// Services of the prefixes of cloud.json like "Google Cloud", all the services if not set
+	// +kubebuilder:validation:Optional
+	Services []string `json:"services,omitempty"`
 }
 
-// IPGroup is a group of IPs with a set expiration time
-type IPGroup struct {
+// AzureProvider is a provider for the Service Tags JSON of Azure. A prefix is whitelisted if its service tag matches
+// both serviceTags and regions.
+type AzureProvider struct {
+	// JsonApi is the url of the Service Tags JSON, like the ServiceTags_Public_<date>.json published weekly by Microsoft
 	// +kubebuilder:validation:Required
-	Name string `json:"name"`
+	// +kubebuilder:validation:MinLength=1
+	JsonApi string `json:"jsonApi"`
+	// ServiceTags are the names of the service tags like AzureFrontDoor.Backend. They have to be set, all the service
+	// tags together are too many cidrs to store in the ProviderSnapshot.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	ServiceTags []string `json:"serviceTags"`
+	// Regions of the service tags like westeurope, all the regions if not set. Global service tags have no region.
+	// +kubebuilder:validation:Optional
+	Regions []string `json:"regions,omitempty"`
+}
+
+// InlineIPGroup is an IPGroup defined inside the IPWhitelistConfig
+type InlineIPGroup struct {
 	// +kubebuilder:validation:Required
-	Expires metav1.Time `json:"expires"`
+	Name        string `json:"name"`
+	IPGroupSpec `json:",inline"`
+}
+
+// IPGroupSpec is a group of IPs with an optional activation and expiration time
+type IPGroupSpec struct {
+	// Expires is the time from which the group is no longer whitelisted, the group never expires if not set
+	// +kubebuilder:validation:Optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+	// NotBefore is the time from which the group is whitelisted, the group is active right away if not set
+	// +kubebuilder:validation:Optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// Schedule limits the group to recurring time windows, the group is whitelisted all the time if not set
+	// +kubebuilder:validation:Optional
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// IncludeGroups are the names of other groups whose cidrs are whitelisted along with this group.
+	// Every included group still has its own expiry, notBefore and schedule applied.
+	// +kubebuilder:validation:Optional
+	IncludeGroups []string `json:"includeGroups,omitempty"`
+	// CIDRS are either plain strings like "10.0.0.0/8" or objects with their own expiry
 	// TODO: add ip validation
 	// +kubebuilder:validation:Optional
-	CIDRS []string `json:"cidrs,omitempty"`
+	CIDRS []CIDR `json:"cidrs,omitempty"`
+}
+
+// CIDR is a single entry of an IPGroup. For compatibility, it can also be given as a plain string, which is the same as
+// an entry with only the cidr set. The schema accepts both forms, so the entries are checked when decoded instead: an
+// entry with an unknown key or without its cidr is refused, rather than silently losing the misspelled field.
+// +kubebuilder:validation:Type=""
+// +kubebuilder:pruning:PreserveUnknownFields
+type CIDR struct {
+	// +kubebuilder:validation:Required
+	CIDR string `json:"cidr"`
+	// Expires is the time from which this CIDR is no longer whitelisted, the expiry of the group still applies
+	// +kubebuilder:validation:Optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+	// Description of who or what the CIDR belongs to
+	// +kubebuilder:validation:Optional
+	Description string `json:"description,omitempty"`
+	// Ticket is the change request or issue the CIDR was requested in
 	// +kubebuilder:validation:Optional
+	Ticket string `json:"ticket,omitempty"`
+}
+
+// Schedule is a set of recurring time windows in a time zone
+type Schedule struct {
+	// TimeZone is the IANA name of the time zone the windows are in, like "Europe/Berlin"
+	// +kubebuilder:default="UTC"
+	// +kubebuilder:validation:Optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Windows during which the group is whitelisted, the group is whitelisted while any of them is open
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Windows []TimeWindow `json:"windows"`
+}
+
+// TimeWindow is a time range repeating on the given days of the week
+type TimeWindow struct {
+	// Days of the week on which the window opens, every day if not set
+	// +kubebuilder:validation:Optional
+	Days []Weekday `json:"days,omitempty"`
+	// Start is the time of the day as HH:MM at which the window opens
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End is the time of the day as HH:MM at which the window closes.
+	// A window ending at or before its start closes on the next day, like 22:00 to 06:00.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// Weekday is a day of the week
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
+// cidrFields has the same fields as CIDR, without its json methods
+type cidrFields CIDR
+
+// errCIDRMissing is returned when decoding an entry of the cidrs without its cidr
+var errCIDRMissing = errors.New("the cidr of the entry is missing")
+
+// UnmarshalJSON accepts both the plain string and the object form of a CIDR, refusing unknown keys and entries
+// without a cidr
+func (c *CIDR) UnmarshalJSON(data []byte) error {
+	var fields cidrFields
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		if err := json.Unmarshal(data, &fields.CIDR); err != nil {
+			return err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&fields); err != nil {
+			return err
+		}
+	}
+	if fields.CIDR == "" {
+		return errCIDRMissing
+	}
+	*c = CIDR(fields)
+	return nil
+}
+
+// MarshalJSON writes the CIDR as a plain string when nothing but the cidr is set, to keep the old form round tripping
+func (c CIDR) MarshalJSON() ([]byte, error) {
+	if c.Expires == nil && c.Description == "" && c.Ticket == "" {
+		return json.Marshal(c.CIDR)
+	}
+	return json.Marshal(cidrFields(c))
 }
 
 type ProviderSelector struct {
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
+	// Filter selects the part of the cidrs of the provider the rule whitelists, the provider is still fetched once for
+	// all the rules using it
+	// +kubebuilder:validation:Optional
+	Filter *ProviderFilter `json:"filter,omitempty"`
+}
+
+// ProviderFilter selects the cidrs of a provider whitelisted by a rule
+type ProviderFilter struct {
+	// Services of the provider, like hooks or actions of GitHub, all the services fetched by the provider if not set
+	// +kubebuilder:validation:Optional
+	Services []string `json:"services,omitempty"`
+	// IPFamily of the cidrs, only IPv4 for Cloudflare and both for the other providers if not set
+	// +kubebuilder:validation:Optional
+	IPFamily IPFamily `json:"ipFamily,omitempty"`
+	// Regions of the cidrs for the cloud providers, all regions if not set
+	// +kubebuilder:validation:Optional
+	Regions []string `json:"regions,omitempty"`
+}
+
+// IPFamily is the address family of cidrs
+// +kubebuilder:validation:Enum=IPv4;IPv6;DualStack
+type IPFamily string
+
+const (
+	IPFamilyIPv4      IPFamily = "IPv4"
+	IPFamilyIPv6      IPFamily = "IPv6"
+	IPFamilyDualStack IPFamily = "DualStack"
+)
+
+// CIDRPolicy bounds the CIDRs that may be whitelisted
+type CIDRPolicy struct {
+	// ForbiddenCIDRs may not be whitelisted, neither themselves, nor as part of a larger CIDR, nor in part or split up
+	// into smaller CIDRs, like 169.254.0.0/16. Forbidding 0.0.0.0/0 forbids all of IPv4, the size of the CIDRs is
+	// bounded by the minimum prefix lengths instead.
+	// +kubebuilder:validation:Optional
+	ForbiddenCIDRs []string `json:"forbiddenCIDRs,omitempty"`
+	// MinPrefixLengthIPv4 is the smallest prefix length, so the largest IPv4 CIDR, that may be whitelisted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=32
+	MinPrefixLengthIPv4 *int32 `json:"minPrefixLengthIPv4,omitempty"`
+	// MinPrefixLengthIPv6 is the smallest prefix length, so the largest IPv6 CIDR, that may be whitelisted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=128
+	MinPrefixLengthIPv6 *int32 `json:"minPrefixLengthIPv6,omitempty"`
+}
+
+// NamespaceIPGroupSelector opts a rule in to the NamespaceIPGroups in the namespace of the ingress
+type NamespaceIPGroupSelector struct {
+	// Selector selects the NamespaceIPGroups by their labels, all of them in the namespace if not set
+	// +kubebuilder:validation:Optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Policy bounds what the NamespaceIPGroups may contain, CIDRs violating it are not whitelisted
+	// +kubebuilder:validation:Optional
+	Policy CIDRPolicy `json:"policy,omitempty"`
+}
+
+// AllowedNamespaces are the namespaces whose ingresses may use a rule, a namespace is allowed if it is named in Names
+// or matches the Selector
+type AllowedNamespaces struct {
+	// Names of the allowed namespaces
+	// +kubebuilder:validation:Optional
+	Names []string `json:"names,omitempty"`
+	// Selector selects the allowed namespaces by their labels
+	// +kubebuilder:validation:Optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 // Rule is mapping of an IPGroup to a set of labels
@@ -117,8 +534,25 @@ type Rule struct {
 	Name string `json:"name"`
 	// +kubebuilder:validation:Required
 	Selector *metav1.LabelSelector `json:"selector"`
+	// AllowedNamespaces restricts the rule to the ingresses in these namespaces, ingresses in any namespace may use it if
+	// not set. A matching ingress in any other namespace is refused the rule, with a Warning event.
+	// +kubebuilder:validation:Optional
+	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+	// IPGroupSelector are the names of the groups to whitelist, either defined in ipGroups or as IPGroup objects
 	// +kubebuilder:validation:Optional
 	IPGroupSelector []string `json:"ipGroupSelector,omitempty"`
+	// IPGroupLabelSelector selects IPGroup objects by their labels, in addition to the ones named in ipGroupSelector
+	// +kubebuilder:validation:Optional
+	IPGroupLabelSelector *metav1.LabelSelector `json:"ipGroupLabelSelector,omitempty"`
+	// NamespaceIPGroups opts the rule in to the NamespaceIPGroups in the namespace of the ingress, they are not
+	// whitelisted if not set
+	// +kubebuilder:validation:Optional
+	NamespaceIPGroups *NamespaceIPGroupSelector `json:"namespaceIPGroups,omitempty"`
+	// MaxAddresses is the most addresses the ipGroups of the rule may whitelist in total, the rule is not applied if
+	// they whitelist more. The providers are not counted.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxAddresses *int64 `json:"maxAddresses,omitempty"`
 	// +kubebuilder:validation:Optional
 	// +listMapKey=name
 	// +listType=map
@@ -134,15 +568,60 @@ type IPWhitelistConfigSpec struct {
 	// +listType=map
 	// +listMapKey=name
 	// +kubebuilder:validation:Optional
-	IPGroups []IPGroup `json:"ipGroups"`
+	IPGroups []InlineIPGroup `json:"ipGroups"`
 
 	// +listMapKey=name
 	// +listType=map
 	// +kubebuilder:validation:Optional
 	Providers []Providers `json:"providers,omitempty"`
+
+	// Policy bounds the CIDRs of all the ipGroups used by the rules, a rule using a group violating it is not applied.
+	// The providers are not checked.
+	// +kubebuilder:validation:Optional
+	Policy *CIDRPolicy `json:"policy,omitempty"`
+}
+
+// Condition types set on the IPWhitelistConfig status
+const (
+	// ConditionIPGroupsExpiring is True when at least one IPGroup expires within the warning window
+	ConditionIPGroupsExpiring = "IPGroupsExpiring"
+	// ConditionPolicyViolated is True when at least one rule violates the policy and is not applied
+	ConditionPolicyViolated = "PolicyViolated"
+	// ConditionProvidersDegraded is True when fetching the cidrs of at least one provider failed the last time
+	ConditionProvidersDegraded = "ProvidersDegraded"
+	// ConditionSiteShieldProposalsPending is True when a Site Shield map has proposed cidrs which are not acknowledged
+	ConditionSiteShieldProposalsPending = "SiteShieldProposalsPending"
+)
+
+// IPWhitelistConfigStatus defines the observed state of IPWhitelistConfig
+type IPWhitelistConfigStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// SiteShieldProposals are the proposed cidrs of the Site Shield maps waiting to be acknowledged
+	// +listType=map
+	// +listMapKey=provider
+	// +listMapKey=mapId
+	// +kubebuilder:validation:Optional
+	SiteShieldProposals []SiteShieldProposal `json:"siteShieldProposals,omitempty"`
+}
+
+// SiteShieldProposal is a Site Shield map with proposed cidrs which are not acknowledged yet
+type SiteShieldProposal struct {
+	// +kubebuilder:validation:Required
+	Provider string `json:"provider"`
+	// +kubebuilder:validation:Required
+	MapID int `json:"mapId"`
+	// +kubebuilder:validation:Optional
+	ProposedCIDRs []string `json:"proposedCidrs,omitempty"`
+	// AcknowledgeRequiredBy is the time by which Akamai requires the proposal to be acknowledged
+	// +kubebuilder:validation:Optional
+	AcknowledgeRequiredBy metav1.Time `json:"acknowledgeRequiredBy,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
 
 // IPWhitelistConfig is the Schema for the ipwhitelistconfigs API
@@ -150,7 +629,8 @@ type IPWhitelistConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec IPWhitelistConfigSpec `json:"spec,omitempty"`
+	Spec   IPWhitelistConfigSpec   `json:"spec,omitempty"`
+	Status IPWhitelistConfigStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,51 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// NamespaceIPGroup is a group of IPs managed by the team owning a namespace. It is only whitelisted for the ingresses
+// in its own namespace, and only by rules opting in to it with namespaceIPGroups. Its includeGroups refer to other
+// NamespaceIPGroups in the same namespace.
+type NamespaceIPGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IPGroupSpec `json:"spec,omitempty"`
+}
+
+// Inline returns the NamespaceIPGroup in the same form as the groups defined in the IPWhitelistConfig
+func (in *NamespaceIPGroup) Inline() InlineIPGroup {
+	return InlineIPGroup{Name: in.Name, IPGroupSpec: in.Spec}
+}
+
+// +kubebuilder:object:root=true
+
+// NamespaceIPGroupList contains a list of NamespaceIPGroup
+type NamespaceIPGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespaceIPGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespaceIPGroup{}, &NamespaceIPGroupList{})
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProviderSnapshotSpec is the list of cidrs of a provider at some point in time
+type ProviderSnapshotSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=akamai;aws;azure;cloudflare;fastly;gcp;github
+	Type ProviderName `json:"type"`
+	// Source the cidrs were fetched from, a snapshot is only used for a provider with the same source
+	// +kubebuilder:validation:Required
+	Source string `json:"source"`
+	// FetchedAt is the time the cidrs were fetched
+	// +kubebuilder:validation:Required
+	FetchedAt metav1.Time `json:"fetchedAt"`
+	// Hash is the sha256 of the sorted cidrs, one per line, followed by a line "service <name> <cidrs>" for every
+	// service and "region <name> <cidrs>" for every region, sorted by name with their cidrs sorted and joined by commas.
+	// The snapshot is refused if it does not match, it is not checked if not set.
+	// +kubebuilder:validation:Optional
+	Hash string `json:"hash,omitempty"`
+	// +kubebuilder:validation:Required
+	CIDRs []string `json:"cidrs"`
+	// Services are the indices into CIDRs of the cidrs of every service of the provider, for the rules filtering the
+	// provider by service
+	// +kubebuilder:validation:Optional
+	Services map[string][]int32 `json:"services,omitempty"`
+	// Regions are the indices into CIDRs of the cidrs of every region of the provider, for the rules filtering the
+	// provider by region
+	// +kubebuilder:validation:Optional
+	Regions map[string][]int32 `json:"regions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source`
+// +kubebuilder:printcolumn:name="Fetched",type=date,JSONPath=`.spec.fetchedAt`
+
+// ProviderSnapshot is the last known good list of cidrs of the provider with the same name. The operator writes it on
+// every successful fetch, and it can be written by hand for providers in the snapshotOnly mode.
+type ProviderSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProviderSnapshotSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ProviderSnapshotList contains a list of ProviderSnapshot
+type ProviderSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProviderSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProviderSnapshot{}, &ProviderSnapshotList{})
+}
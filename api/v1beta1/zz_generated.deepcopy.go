@@ -21,11 +21,42 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSProvider) DeepCopyInto(out *AWSProvider) {
+	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NetworkBorderGroups != nil {
+		in, out := &in.NetworkBorderGroups, &out.NetworkBorderGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSProvider.
+func (in *AWSProvider) DeepCopy() *AWSProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AkamaiProvider) DeepCopyInto(out *AkamaiProvider) {
 	*out = *in
@@ -34,6 +65,11 @@ func (in *AkamaiProvider) DeepCopyInto(out *AkamaiProvider) {
 		*out = new(intstr.IntOrString)
 		**out = **in
 	}
+	if in.MapIds != nil {
+		in, out := &in.MapIds, &out.MapIds
+		*out = make([]intstr.IntOrString, len(*in))
+		copy(*out, *in)
+	}
 	if in.Host != nil {
 		in, out := &in.Host, &out.Host
 		*out = new(SecretKeySelector)
@@ -54,6 +90,11 @@ func (in *AkamaiProvider) DeepCopyInto(out *AkamaiProvider) {
 		*out = new(SecretKeySelector)
 		**out = **in
 	}
+	if in.EdgercSecretRef != nil {
+		in, out := &in.EdgercSecretRef, &out.EdgercSecretRef
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AkamaiProvider.
@@ -66,6 +107,130 @@ func (in *AkamaiProvider) DeepCopy() *AkamaiProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllowedNamespaces) DeepCopyInto(out *AllowedNamespaces) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllowedNamespaces.
+func (in *AllowedNamespaces) DeepCopy() *AllowedNamespaces {
+	if in == nil {
+		return nil
+	}
+	out := new(AllowedNamespaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureProvider) DeepCopyInto(out *AzureProvider) {
+	*out = *in
+	if in.ServiceTags != nil {
+		in, out := &in.ServiceTags, &out.ServiceTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureProvider.
+func (in *AzureProvider) DeepCopy() *AzureProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CABundleSource) DeepCopyInto(out *CABundleSource) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ConfigMapKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CABundleSource.
+func (in *CABundleSource) DeepCopy() *CABundleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(CABundleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDR) DeepCopyInto(out *CIDR) {
+	*out = *in
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDR.
+func (in *CIDR) DeepCopy() *CIDR {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDR)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRPolicy) DeepCopyInto(out *CIDRPolicy) {
+	*out = *in
+	if in.ForbiddenCIDRs != nil {
+		in, out := &in.ForbiddenCIDRs, &out.ForbiddenCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinPrefixLengthIPv4 != nil {
+		in, out := &in.MinPrefixLengthIPv4, &out.MinPrefixLengthIPv4
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinPrefixLengthIPv6 != nil {
+		in, out := &in.MinPrefixLengthIPv6, &out.MinPrefixLengthIPv6
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRPolicy.
+func (in *CIDRPolicy) DeepCopy() *CIDRPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudflareProvider) DeepCopyInto(out *CloudflareProvider) {
 	*out = *in
@@ -81,6 +246,37 @@ func (in *CloudflareProvider) DeepCopy() *CloudflareProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeySelector) DeepCopyInto(out *ConfigMapKeySelector) {
+	*out = *in
+	out.ConfigMap = in.ConfigMap
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeySelector.
+func (in *ConfigMapKeySelector) DeepCopy() *ConfigMapKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FastlyProvider) DeepCopyInto(out *FastlyProvider) {
 	*out = *in
@@ -96,6 +292,31 @@ func (in *FastlyProvider) DeepCopy() *FastlyProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPProvider) DeepCopyInto(out *GCPProvider) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPProvider.
+func (in *GCPProvider) DeepCopy() *GCPProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubProvider) DeepCopyInto(out *GithubProvider) {
 	*out = *in
@@ -104,6 +325,11 @@ func (in *GithubProvider) DeepCopyInto(out *GithubProvider) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubProvider.
@@ -119,12 +345,9 @@ func (in *GithubProvider) DeepCopy() *GithubProvider {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPGroup) DeepCopyInto(out *IPGroup) {
 	*out = *in
-	in.Expires.DeepCopyInto(&out.Expires)
-	if in.CIDRS != nil {
-		in, out := &in.CIDRS, &out.CIDRS
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPGroup.
@@ -137,12 +360,93 @@ func (in *IPGroup) DeepCopy() *IPGroup {
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPGroupList) DeepCopyInto(out *IPGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPGroupList.
+func (in *IPGroupList) DeepCopy() *IPGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPGroupSpec) DeepCopyInto(out *IPGroupSpec) {
+	*out = *in
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(Schedule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IncludeGroups != nil {
+		in, out := &in.IncludeGroups, &out.IncludeGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CIDRS != nil {
+		in, out := &in.CIDRS, &out.CIDRS
+		*out = make([]CIDR, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPGroupSpec.
+func (in *IPGroupSpec) DeepCopy() *IPGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPWhitelistConfig) DeepCopyInto(out *IPWhitelistConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWhitelistConfig.
@@ -207,7 +511,7 @@ func (in *IPWhitelistConfigSpec) DeepCopyInto(out *IPWhitelistConfigSpec) {
 	}
 	if in.IPGroups != nil {
 		in, out := &in.IPGroups, &out.IPGroups
-		*out = make([]IPGroup, len(*in))
+		*out = make([]InlineIPGroup, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -219,6 +523,11 @@ func (in *IPWhitelistConfigSpec) DeepCopyInto(out *IPWhitelistConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(CIDRPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWhitelistConfigSpec.
@@ -231,9 +540,214 @@ func (in *IPWhitelistConfigSpec) DeepCopy() *IPWhitelistConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPWhitelistConfigStatus) DeepCopyInto(out *IPWhitelistConfigStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SiteShieldProposals != nil {
+		in, out := &in.SiteShieldProposals, &out.SiteShieldProposals
+		*out = make([]SiteShieldProposal, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWhitelistConfigStatus.
+func (in *IPWhitelistConfigStatus) DeepCopy() *IPWhitelistConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IPWhitelistConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InlineIPGroup) DeepCopyInto(out *InlineIPGroup) {
+	*out = *in
+	in.IPGroupSpec.DeepCopyInto(&out.IPGroupSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InlineIPGroup.
+func (in *InlineIPGroup) DeepCopy() *InlineIPGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(InlineIPGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceIPGroup) DeepCopyInto(out *NamespaceIPGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceIPGroup.
+func (in *NamespaceIPGroup) DeepCopy() *NamespaceIPGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceIPGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespaceIPGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceIPGroupList) DeepCopyInto(out *NamespaceIPGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NamespaceIPGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceIPGroupList.
+func (in *NamespaceIPGroupList) DeepCopy() *NamespaceIPGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceIPGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespaceIPGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceIPGroupSelector) DeepCopyInto(out *NamespaceIPGroupSelector) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Policy.DeepCopyInto(&out.Policy)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceIPGroupSelector.
+func (in *NamespaceIPGroupSelector) DeepCopy() *NamespaceIPGroupSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceIPGroupSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderFilter) DeepCopyInto(out *ProviderFilter) {
+	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderFilter.
+func (in *ProviderFilter) DeepCopy() *ProviderFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderHTTPClient) DeepCopyInto(out *ProviderHTTPClient) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = new(CABundleSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientCertificate != nil {
+		in, out := &in.ClientCertificate, &out.ClientCertificate
+		*out = new(corev1.SecretReference)
+		**out = **in
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(ProviderRetry)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderHTTPClient.
+func (in *ProviderHTTPClient) DeepCopy() *ProviderHTTPClient {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderHTTPClient)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderRetry) DeepCopyInto(out *ProviderRetry) {
+	*out = *in
+	out.Backoff = in.Backoff
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderRetry.
+func (in *ProviderRetry) DeepCopy() *ProviderRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderSelector) DeepCopyInto(out *ProviderSelector) {
 	*out = *in
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(ProviderFilter)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSelector.
@@ -246,6 +760,117 @@ func (in *ProviderSelector) DeepCopy() *ProviderSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSnapshot) DeepCopyInto(out *ProviderSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSnapshot.
+func (in *ProviderSnapshot) DeepCopy() *ProviderSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProviderSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSnapshotList) DeepCopyInto(out *ProviderSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProviderSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSnapshotList.
+func (in *ProviderSnapshotList) DeepCopy() *ProviderSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProviderSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSnapshotSpec) DeepCopyInto(out *ProviderSnapshotSpec) {
+	*out = *in
+	in.FetchedAt.DeepCopyInto(&out.FetchedAt)
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make(map[string][]int32, len(*in))
+		for key, val := range *in {
+			var outVal []int32
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]int32, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make(map[string][]int32, len(*in))
+		for key, val := range *in {
+			var outVal []int32
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]int32, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSnapshotSpec.
+func (in *ProviderSnapshotSpec) DeepCopy() *ProviderSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Providers) DeepCopyInto(out *Providers) {
 	*out = *in
@@ -253,6 +878,28 @@ func (in *Providers) DeepCopyInto(out *Providers) {
 	out.Cloudflare = in.Cloudflare
 	out.Fastly = in.Fastly
 	in.Github.DeepCopyInto(&out.Github)
+	in.AWS.DeepCopyInto(&out.AWS)
+	in.GCP.DeepCopyInto(&out.GCP)
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(AzureProvider)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxShrinkPercent != nil {
+		in, out := &in.MaxShrinkPercent, &out.MaxShrinkPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinEntries != nil {
+		in, out := &in.MinEntries, &out.MinEntries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.HTTPClient != nil {
+		in, out := &in.HTTPClient, &out.HTTPClient
+		*out = new(ProviderHTTPClient)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Providers.
@@ -273,15 +920,37 @@ func (in *Rule) DeepCopyInto(out *Rule) {
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(AllowedNamespaces)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.IPGroupSelector != nil {
 		in, out := &in.IPGroupSelector, &out.IPGroupSelector
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IPGroupLabelSelector != nil {
+		in, out := &in.IPGroupLabelSelector, &out.IPGroupLabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceIPGroups != nil {
+		in, out := &in.NamespaceIPGroups, &out.NamespaceIPGroups
+		*out = new(NamespaceIPGroupSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxAddresses != nil {
+		in, out := &in.MaxAddresses, &out.MaxAddresses
+		*out = new(int64)
+		**out = **in
+	}
 	if in.ProviderSelector != nil {
 		in, out := &in.ProviderSelector, &out.ProviderSelector
 		*out = make([]ProviderSelector, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -295,6 +964,28 @@ func (in *Rule) DeepCopy() *Rule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Schedule) DeepCopyInto(out *Schedule) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]TimeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Schedule.
+func (in *Schedule) DeepCopy() *Schedule {
+	if in == nil {
+		return nil
+	}
+	out := new(Schedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
 	*out = *in
@@ -310,3 +1001,44 @@ func (in *SecretKeySelector) DeepCopy() *SecretKeySelector {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteShieldProposal) DeepCopyInto(out *SiteShieldProposal) {
+	*out = *in
+	if in.ProposedCIDRs != nil {
+		in, out := &in.ProposedCIDRs, &out.ProposedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.AcknowledgeRequiredBy.DeepCopyInto(&out.AcknowledgeRequiredBy)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteShieldProposal.
+func (in *SiteShieldProposal) DeepCopy() *SiteShieldProposal {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteShieldProposal)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeWindow) DeepCopyInto(out *TimeWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeWindow.
+func (in *TimeWindow) DeepCopy() *TimeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
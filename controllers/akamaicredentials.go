@@ -0,0 +1,149 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/corbaltcode/go-akamai"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const defaultEdgercSection = "default"
+
+func (r *IPWhitelistConfigReconciler) getsiteShieldClient(ctx context.Context, httpClient *http.Client, provider beta1.AkamaiProvider) (*siteShieldClient, error) {
+	cred, err := r.akamaiCredentials(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	return &siteShieldClient{credentials: cred, httpClient: httpClient}, nil
+}
+
+// akamaiCredentials returns the EdgeGrid credentials of the provider. They are read from the environment, from an
+// .edgerc file mounted into the pod, from an .edgerc file in a Secret or from the four SecretRefs, only one of which may
+// be set. Only the last two need the operator to read Secrets.
+func (r *IPWhitelistConfigReconciler) akamaiCredentials(ctx context.Context, provider beta1.AkamaiProvider) (akamai.Credentials, error) {
+	var sources []string
+	for _, source := range []struct {
+		field string
+		set   bool
+	}{
+		{field: "credentialsFromEnv", set: provider.CredentialsFromEnv},
+		{field: "edgercPath", set: provider.EdgercPath != ""},
+		{field: "edgercSecretRef", set: provider.EdgercSecretRef != nil},
+		{field: "the SecretRefs", set: provider.Host != nil || provider.ClientToken != nil || provider.ClientSecret != nil || provider.AccessToken != nil},
+	} {
+		if source.set {
+			sources = append(sources, source.field)
+		}
+	}
+	if len(sources) > 1 {
+		return akamai.Credentials{}, fmt.Errorf("the credentials are set by %s, only one of them may be set", strings.Join(sources, ", "))
+	}
+
+	section := provider.EdgercSection
+	if section == "" {
+		section = defaultEdgercSection
+	}
+	switch {
+	case provider.CredentialsFromEnv:
+		return akamaiCredentialsFromEnv(section)
+	case provider.EdgercPath != "":
+		cred, err := akamai.LoadCredentialsFromEdgercFile(provider.EdgercPath, section)
+		if err != nil {
+			return akamai.Credentials{}, fmt.Errorf("failed to read the credentials from the edgerc file %s: %w", provider.EdgercPath, err)
+		}
+		return cred, nil
+	case provider.EdgercSecretRef != nil:
+		edgerc, err := r.readSecretKey(ctx, provider.EdgercSecretRef)
+		if err != nil {
+			return akamai.Credentials{}, err
+		}
+		cred, err := akamai.LoadCredentialsFromEdgerc(strings.NewReader(edgerc), section)
+		if err != nil {
+			return akamai.Credentials{}, fmt.Errorf("failed to read the credentials from the edgerc in the secret %s/%s: %w",
+				provider.EdgercSecretRef.Secret.Namespace, provider.EdgercSecretRef.Secret.Name, err)
+		}
+		return cred, nil
+	}
+
+	var cred akamai.Credentials
+	refs := []struct {
+		field string
+		ref   *beta1.SecretKeySelector
+		value *string
+	}{
+		{field: "serviceConsumerDomainRef", ref: provider.Host, value: &cred.Host},
+		{field: "clientTokenSecretRef", ref: provider.ClientToken, value: &cred.ClientToken},
+		{field: "clientSecretSecretRef", ref: provider.ClientSecret, value: &cred.ClientSecret},
+		{field: "accessTokenSecretRef", ref: provider.AccessToken, value: &cred.AccessToken},
+	}
+	var missing []string
+	for _, ref := range refs {
+		if ref.ref == nil {
+			missing = append(missing, ref.field)
+		}
+	}
+	if len(missing) > 0 {
+		return akamai.Credentials{}, fmt.Errorf("the credentials are missing %s, set them or one of edgercSecretRef, edgercPath or credentialsFromEnv",
+			strings.Join(missing, ", "))
+	}
+	for _, ref := range refs {
+		value, err := r.readSecretKey(ctx, ref.ref)
+		if err != nil {
+			return akamai.Credentials{}, err
+		}
+		*ref.value = value
+	}
+	return cred, nil
+}
+
+// akamaiCredentialsFromEnv reads the credentials of the section from the environment, like the Akamai CLIs do
+func akamaiCredentialsFromEnv(section string) (akamai.Credentials, error) {
+	prefix := "AKAMAI_"
+	if section != defaultEdgercSection {
+		prefix += strings.ToUpper(section) + "_"
+	}
+	cred := akamai.Credentials{
+		Host:         os.Getenv(prefix + "HOST"),
+		ClientToken:  os.Getenv(prefix + "CLIENT_TOKEN"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		AccessToken:  os.Getenv(prefix + "ACCESS_TOKEN"),
+	}
+	var missing []string
+	for name, value := range map[string]string{
+		"HOST":          cred.Host,
+		"CLIENT_TOKEN":  cred.ClientToken,
+		"CLIENT_SECRET": cred.ClientSecret,
+		"ACCESS_TOKEN":  cred.AccessToken,
+	} {
+		if value == "" {
+			missing = append(missing, prefix+name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return akamai.Credentials{}, fmt.Errorf("the environment variables %s are not set", strings.Join(missing, ", "))
+	}
+	return cred, nil
+}
@@ -0,0 +1,110 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/corbaltcode/go-akamai"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("Akamai credentials", func() {
+	ctx := context.Background()
+	const edgerc = `[default]
+client_secret = default-secret
+host = akab-default.luna.akamaiapis.net
+access_token = default-access
+client_token = default-token
+
+[siteshield]
+client_secret = secret
+host = akab-siteshield.luna.akamaiapis.net
+access_token = access
+client_token = token
+`
+	siteShield := akamai.Credentials{
+		ClientSecret: "secret",
+		AccessToken:  "access",
+		ClientToken:  "token",
+		Host:         "akab-siteshield.luna.akamaiapis.net",
+	}
+	var r *IPWhitelistConfigReconciler
+
+	BeforeEach(func() {
+		r = &IPWhitelistConfigReconciler{
+			Client: fake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "akamai", Namespace: "default"},
+				Data:       map[string][]byte{".edgerc": []byte(edgerc)},
+			}).Build(),
+			Log: logr.Discard(),
+		}
+	})
+
+	It("should read a section of an .edgerc in a Secret", func() {
+		provider := beta1.AkamaiProvider{
+			EdgercSecretRef: &beta1.SecretKeySelector{Secret: corev1.SecretReference{Name: "akamai", Namespace: "default"}, Key: ".edgerc"},
+			EdgercSection:   "siteshield",
+		}
+		Expect(r.akamaiCredentials(ctx, provider)).To(Equal(siteShield))
+
+		provider.EdgercSection = ""
+		cred, err := r.akamaiCredentials(ctx, provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cred.Host).To(Equal("akab-default.luna.akamaiapis.net"))
+
+		provider.EdgercSection = "papi"
+		_, err = r.akamaiCredentials(ctx, provider)
+		Expect(err).To(MatchError(ContainSubstring(`no section "papi"`)))
+	})
+	It("should read an .edgerc mounted into the pod", func() {
+		path := filepath.Join(GinkgoT().TempDir(), ".edgerc")
+		Expect(os.WriteFile(path, []byte(edgerc), 0o600)).To(Succeed())
+		Expect(r.akamaiCredentials(ctx, beta1.AkamaiProvider{EdgercPath: path, EdgercSection: "siteshield"})).To(Equal(siteShield))
+	})
+	It("should read the credentials of the section from the environment", func() {
+		GinkgoT().Setenv("AKAMAI_SITESHIELD_HOST", siteShield.Host)
+		GinkgoT().Setenv("AKAMAI_SITESHIELD_CLIENT_TOKEN", siteShield.ClientToken)
+		GinkgoT().Setenv("AKAMAI_SITESHIELD_CLIENT_SECRET", siteShield.ClientSecret)
+		provider := beta1.AkamaiProvider{CredentialsFromEnv: true, EdgercSection: "siteshield"}
+		_, err := r.akamaiCredentials(ctx, provider)
+		Expect(err).To(MatchError("the environment variables AKAMAI_SITESHIELD_ACCESS_TOKEN are not set"))
+
+		GinkgoT().Setenv("AKAMAI_SITESHIELD_ACCESS_TOKEN", siteShield.AccessToken)
+		Expect(r.akamaiCredentials(ctx, provider)).To(Equal(siteShield))
+	})
+	It("should name the missing SecretRefs", func() {
+		_, err := r.akamaiCredentials(ctx, beta1.AkamaiProvider{})
+		Expect(err).To(MatchError(ContainSubstring("missing serviceConsumerDomainRef, clientTokenSecretRef, clientSecretSecretRef, accessTokenSecretRef")))
+	})
+	It("should refuse more than one source of credentials", func() {
+		ref := &beta1.SecretKeySelector{Secret: corev1.SecretReference{Name: "akamai", Namespace: "default"}, Key: ".edgerc"}
+		_, err := r.akamaiCredentials(ctx, beta1.AkamaiProvider{CredentialsFromEnv: true, EdgercSecretRef: ref})
+		Expect(err).To(MatchError("the credentials are set by credentialsFromEnv, edgercSecretRef, only one of them may be set"))
+
+		_, err = r.akamaiCredentials(ctx, beta1.AkamaiProvider{EdgercSecretRef: ref, ClientToken: ref})
+		Expect(err).To(MatchError(ContainSubstring("edgercSecretRef, the SecretRefs")))
+	})
+})
@@ -0,0 +1,131 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// awsIPRanges is the ip-ranges.json of AWS
+type awsIPRanges struct {
+	SyncToken    string           `json:"syncToken"`
+	Prefixes     []awsIPRange     `json:"prefixes"`
+	IPv6Prefixes []awsIPv6IPRange `json:"ipv6_prefixes"`
+}
+
+type awsIPRange struct {
+	IPPrefix           string `json:"ip_prefix"`
+	Region             string `json:"region"`
+	Service            string `json:"service"`
+	NetworkBorderGroup string `json:"network_border_group"`
+}
+
+type awsIPv6IPRange struct {
+	IPv6Prefix         string `json:"ipv6_prefix"`
+	Region             string `json:"region"`
+	Service            string `json:"service"`
+	NetworkBorderGroup string `json:"network_border_group"`
+}
+
+func getAWSCidrs(ctx context.Context, httpClient *http.Client, cached *cachedResponse, provider beta1.AWSProvider) (_ []netaddr.IPPrefix, _ cidrTags, err error) {
+	ctx, span := tracer.Start(ctx, "getAWSCidrs", trace.WithAttributes(attribute.String("url", provider.JsonApi)))
+	defer func() { endSpan(span, err) }()
+
+	key := strings.Join([]string{
+		provider.JsonApi,
+		strings.Join(provider.Services, ","),
+		strings.Join(provider.Regions, ","),
+		strings.Join(provider.NetworkBorderGroups, ","),
+	}, " ")
+	// the syncToken changes with every publication, the same one means the same prefixes
+	return fetchVersionedDocument(ctx, httpClient, cached, provider.JsonApi, key, "syncToken", func(body []byte) ([]netaddr.IPPrefix, cidrTags, error) {
+		var ranges awsIPRanges
+		if err := jsoniter.Unmarshal(body, &ranges); err != nil {
+			return nil, cidrTags{}, badResponse("failed to unmarshal response body from aws: %v", err)
+		}
+		if ranges.SyncToken == "" || len(ranges.Prefixes) == 0 {
+			return nil, cidrTags{}, badResponse("aws ip ranges response has no syncToken or no prefixes")
+		}
+		return ranges.filter(provider)
+	})
+}
+
+// filter returns the IPv4 and IPv6 prefixes matching the services, regions and network border groups of the provider,
+// tagged with their services and regions
+func (r awsIPRanges) filter(provider beta1.AWSProvider) ([]netaddr.IPPrefix, cidrTags, error) {
+	ranges := slices.Clone(r.Prefixes)
+	for _, prefix := range r.IPv6Prefixes {
+		ranges = append(ranges, awsIPRange{
+			IPPrefix:           prefix.IPv6Prefix,
+			Region:             prefix.Region,
+			Service:            prefix.Service,
+			NetworkBorderGroup: prefix.NetworkBorderGroup,
+		})
+	}
+
+	services, regions, borderGroups := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, prefix := range ranges {
+		services[prefix.Service] = true
+		regions[prefix.Region] = true
+		borderGroups[prefix.NetworkBorderGroup] = true
+	}
+	filters := []documentFilter{
+		{kind: "services", values: provider.Services, known: services},
+		{kind: "regions", values: provider.Regions, known: regions},
+		{kind: "network border groups", values: provider.NetworkBorderGroups, known: borderGroups},
+	}
+	if err := checkDocumentFilters("aws ip ranges", filters...); err != nil {
+		return nil, cidrTags{}, err
+	}
+
+	var cidrs []netaddr.IPPrefix
+	var tags cidrTags
+	seen := make(map[netaddr.IPPrefix]bool)
+	for _, prefix := range ranges {
+		if !matches(provider.Services, prefix.Service) ||
+			!matches(provider.Regions, prefix.Region) ||
+			!matches(provider.NetworkBorderGroups, prefix.NetworkBorderGroup) {
+			continue
+		}
+		cidr, err := netaddr.ParseIPPrefix(prefix.IPPrefix)
+		if err != nil {
+			return nil, cidrTags{}, badResponse("unable to parse ip %s: %v", prefix.IPPrefix, err)
+		}
+		// a prefix is listed once for every service it is in, like AMAZON and EC2
+		tags.addService(prefix.Service, cidr)
+		if seen[cidr] {
+			continue
+		}
+		seen[cidr] = true
+		tags.addRegion(prefix.Region, cidr)
+		cidrs = append(cidrs, cidr)
+	}
+	if len(cidrs) == 0 {
+		return nil, cidrTags{}, noDocumentMatch("aws ip ranges", filters...)
+	}
+	return cidrs, tags, nil
+}
@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const awsIPRangesJSON = `{
+  "syncToken": "1700000000",
+  "createDate": "2023-11-14-22-13-20",
+  "prefixes": [
+    {"ip_prefix": "3.5.140.0/22", "region": "ap-northeast-2", "service": "AMAZON", "network_border_group": "ap-northeast-2"},
+    {"ip_prefix": "15.230.39.0/24", "region": "us-west-2", "service": "AMAZON", "network_border_group": "us-west-2-lax-1"},
+    {"ip_prefix": "15.230.39.0/24", "region": "us-west-2", "service": "EC2", "network_border_group": "us-west-2-lax-1"},
+    {"ip_prefix": "13.113.196.64/26", "region": "GLOBAL", "service": "CLOUDFRONT_ORIGIN_FACING", "network_border_group": "GLOBAL"},
+    {"ip_prefix": "54.228.16.0/26", "region": "eu-west-1", "service": "ROUTE53_HEALTHCHECKS", "network_border_group": "eu-west-1"}
+  ],
+  "ipv6_prefixes": [
+    {"ipv6_prefix": "2600:9000:ddd::/48", "region": "GLOBAL", "service": "CLOUDFRONT_ORIGIN_FACING", "network_border_group": "GLOBAL"},
+    {"ipv6_prefix": "2a05:d018::/36", "region": "eu-west-1", "service": "EC2", "network_border_group": "eu-west-1"}
+  ]
+}`
+
+var _ = Describe("AWS provider", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = serveDocument("application/json", awsIPRangesJSON)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should whitelist the IPv4 and IPv6 prefixes of the services", func() {
+		cidrs, tags, err := getAWSCidrs(ctx, http.DefaultClient, nil, beta1.AWSProvider{JsonApi: server.URL, Services: []string{"CLOUDFRONT_ORIGIN_FACING"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("13.113.196.64/26"),
+			netaddr.MustParseIPPrefix("2600:9000:ddd::/48"),
+		}))
+		Expect(tags.regions).To(HaveKey("GLOBAL"))
+	})
+	It("should filter by region and network border group", func() {
+		cidrs, tags, err := getAWSCidrs(ctx, http.DefaultClient, nil, beta1.AWSProvider{JsonApi: server.URL, Regions: []string{"us-west-2", "eu-west-1"}, NetworkBorderGroups: []string{"us-west-2-lax-1"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("15.230.39.0/24")}))
+		Expect(tags.services).To(HaveKey("AMAZON"))
+		Expect(tags.services).To(HaveKey("EC2"))
+
+		By("failing on a service not in the ip ranges")
+		_, _, err = getAWSCidrs(ctx, http.DefaultClient, nil, beta1.AWSProvider{JsonApi: server.URL, Services: []string{"CLOUDFRONT_ORIGIN_FACNG"}})
+		Expect(err).To(MatchError(ContainSubstring("services CLOUDFRONT_ORIGIN_FACNG not found in the aws ip ranges")))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+	})
+	It("should fail on filters which are all in the ip ranges but match no prefix together", func() {
+		// the origin facing ranges of cloudfront are all GLOBAL
+		_, _, err := getAWSCidrs(ctx, http.DefaultClient, nil, beta1.AWSProvider{JsonApi: server.URL, Services: []string{"CLOUDFRONT_ORIGIN_FACING"}, Regions: []string{"eu-west-1"}})
+		Expect(err).To(MatchError(ContainSubstring("no entry of the aws ip ranges matches services CLOUDFRONT_ORIGIN_FACING and regions eu-west-1")))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+	})
+})
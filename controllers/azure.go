@@ -0,0 +1,123 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// azureServiceTags is the Service Tags JSON of Azure
+type azureServiceTags struct {
+	ChangeNumber int               `json:"changeNumber"`
+	Cloud        string            `json:"cloud"`
+	Values       []azureServiceTag `json:"values"`
+}
+
+type azureServiceTag struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Region          string   `json:"region"`
+		AddressPrefixes []string `json:"addressPrefixes"`
+	} `json:"properties"`
+}
+
+func getAzureCidrs(ctx context.Context, httpClient *http.Client, cached *cachedResponse, provider beta1.AzureProvider) (_ []netaddr.IPPrefix, _ cidrTags, err error) {
+	ctx, span := tracer.Start(ctx, "getAzureCidrs", trace.WithAttributes(attribute.String("url", provider.JsonApi)))
+	defer func() { endSpan(span, err) }()
+
+	// the schema requires both, they are checked again for configs written before it did
+	if provider.JsonApi == "" {
+		return nil, cidrTags{}, fmt.Errorf("the jsonApi of the Service Tags JSON is required, it changes with every weekly publication")
+	}
+	if len(provider.ServiceTags) == 0 {
+		return nil, cidrTags{}, fmt.Errorf("the serviceTags of the azure provider are required, all the service tags are too many cidrs to whitelist")
+	}
+	key := strings.Join([]string{
+		provider.JsonApi,
+		strings.Join(provider.ServiceTags, ","),
+		strings.Join(provider.Regions, ","),
+	}, " ")
+	// the changeNumber of the document goes up whenever any of its service tags changes
+	return fetchVersionedDocument(ctx, httpClient, cached, provider.JsonApi, key, "changeNumber", func(body []byte) ([]netaddr.IPPrefix, cidrTags, error) {
+		var serviceTags azureServiceTags
+		if err := jsoniter.Unmarshal(body, &serviceTags); err != nil {
+			return nil, cidrTags{}, badResponse("failed to unmarshal response body from azure: %v", err)
+		}
+		if serviceTags.ChangeNumber == 0 || len(serviceTags.Values) == 0 {
+			return nil, cidrTags{}, badResponse("azure service tags response has no changeNumber or no service tags")
+		}
+		return serviceTags.filter(provider)
+	})
+}
+
+// filter returns the IPv4 and IPv6 prefixes of the service tags matching the service tags and regions of the
+// provider, tagged with the names of their service tags as services and with their regions
+func (t azureServiceTags) filter(provider beta1.AzureProvider) ([]netaddr.IPPrefix, cidrTags, error) {
+	names, regions := map[string]bool{}, map[string]bool{}
+	for _, tag := range t.Values {
+		names[tag.Name] = true
+		if tag.Properties.Region != "" {
+			regions[tag.Properties.Region] = true
+		}
+	}
+	filters := []documentFilter{
+		{kind: "service tags", values: provider.ServiceTags, known: names},
+		{kind: "regions", values: provider.Regions, known: regions},
+	}
+	if err := checkDocumentFilters("azure service tags", filters...); err != nil {
+		return nil, cidrTags{}, err
+	}
+
+	var cidrs []netaddr.IPPrefix
+	var tags cidrTags
+	seen := make(map[netaddr.IPPrefix]bool)
+	for _, tag := range t.Values {
+		if !matches(provider.ServiceTags, tag.Name) || !matches(provider.Regions, tag.Properties.Region) {
+			continue
+		}
+		for _, prefix := range tag.Properties.AddressPrefixes {
+			cidr, err := netaddr.ParseIPPrefix(prefix)
+			if err != nil {
+				return nil, cidrTags{}, badResponse("unable to parse ip %s: %v", prefix, err)
+			}
+			// a prefix is in its regional service tag as well as in the global one, like AzureCloud.westeurope and
+			// AzureCloud
+			tags.addService(tag.Name, cidr)
+			if tag.Properties.Region != "" {
+				tags.addRegion(tag.Properties.Region, cidr)
+			}
+			if !seen[cidr] {
+				seen[cidr] = true
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	if len(cidrs) == 0 {
+		return nil, cidrTags{}, noDocumentMatch("azure service tags", filters...)
+	}
+	return cidrs, tags, nil
+}
@@ -0,0 +1,92 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const azureServiceTagsJSON = `{
+  "changeNumber": 300,
+  "cloud": "Public",
+  "values": [
+    {"name": "AzureFrontDoor.Backend", "id": "AzureFrontDoor.Backend", "properties": {"changeNumber": 12, "region": "", "addressPrefixes": ["13.73.248.8/29", "2603:1000:4::/64"]}},
+    {"name": "AzureCloud.westeurope", "id": "AzureCloud.westeurope", "properties": {"changeNumber": 40, "region": "westeurope", "addressPrefixes": ["13.69.0.0/17"]}},
+    {"name": "AzureCloud.northeurope", "id": "AzureCloud.northeurope", "properties": {"changeNumber": 41, "region": "northeurope", "addressPrefixes": ["13.69.128.0/17"]}},
+    {"name": "AzureCloud", "id": "AzureCloud", "properties": {"changeNumber": 90, "region": "", "addressPrefixes": ["13.69.0.0/17", "13.69.128.0/17"]}}
+  ]
+}`
+
+var _ = Describe("Azure provider", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = serveDocument("application/octet-stream", azureServiceTagsJSON)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should whitelist the IPv4 and IPv6 prefixes of the service tags", func() {
+		cidrs, _, err := getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{JsonApi: server.URL, ServiceTags: []string{"AzureFrontDoor.Backend"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("13.73.248.8/29"),
+			netaddr.MustParseIPPrefix("2603:1000:4::/64"),
+		}))
+
+		By("failing on a service tag not in the document")
+		_, _, err = getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{JsonApi: server.URL, ServiceTags: []string{"AzureFrontdoor.Backend"}})
+		Expect(err).To(MatchError(ContainSubstring("service tags AzureFrontdoor.Backend not found in the azure service tags")))
+	})
+	It("should filter by region", func() {
+		cidrs, tags, err := getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{JsonApi: server.URL, ServiceTags: []string{"AzureCloud", "AzureCloud.westeurope"}, Regions: []string{"westeurope"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("13.69.0.0/17")}))
+		Expect(tags.regions).To(HaveKey("westeurope"))
+
+		By("letting a rule take a service tag of a provider with several of them")
+		p := beta1.Providers{Name: "azure", Type: beta1.Azure}
+		all, tags, err := getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{JsonApi: server.URL, ServiceTags: []string{"AzureFrontDoor.Backend", "AzureCloud", "AzureCloud.northeurope"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(all).To(HaveLen(4))
+		Expect(filterProviderCIDRs(p, &beta1.ProviderFilter{Services: []string{"AzureCloud"}, Regions: []string{"northeurope"}}, all, tags)).
+			To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("13.69.128.0/17")}))
+	})
+	It("should fail on filters which are all in the document but match no prefix together", func() {
+		// the service tag of the backends of front door has no region
+		_, _, err := getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{JsonApi: server.URL, ServiceTags: []string{"AzureFrontDoor.Backend"}, Regions: []string{"westeurope"}})
+		Expect(err).To(MatchError(ContainSubstring("no entry of the azure service tags matches service tags AzureFrontDoor.Backend and regions westeurope")))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+	})
+	It("should require the url of the Service Tags JSON", func() {
+		_, _, err := getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{})
+		Expect(err).To(MatchError(ContainSubstring("jsonApi of the Service Tags JSON is required")))
+
+		By("requiring the service tags")
+		_, _, err = getAzureCidrs(ctx, http.DefaultClient, nil, beta1.AzureProvider{JsonApi: server.URL, Regions: []string{"westeurope"}})
+		Expect(err).To(MatchError(ContainSubstring("serviceTags of the azure provider are required")))
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"inet.af/netaddr"
+)
+
+// responseCache keeps the last response of every http provider by name, the zero value is ready to use
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+// entry returns the cached response of the provider
+func (c *responseCache) entry(name string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedResponse)
+	}
+	entry, ok := c.entries[name]
+	if !ok {
+		entry = &cachedResponse{name: name}
+		c.entries[name] = entry
+	}
+	return entry
+}
+
+// cachedResponse is the ETag and Last-Modified of the last response of a provider, along with the cidrs parsed from
+// it, so that a request answered with a 304 does not parse the response again. A nil cachedResponse sends no
+// conditional requests.
+type cachedResponse struct {
+	name string
+
+	mu sync.Mutex
+	// key identifies what the cidrs were parsed for, like the url and the services of the provider
+	key          string
+	etag         string
+	lastModified string
+	// version is the version the provider gives its document, like the syncToken of AWS
+	version string
+	cidrs   []netaddr.IPPrefix
+	tags    cidrTags
+}
+
+// apply adds the conditional headers to the request, if there is a cached response for the key
+func (c *cachedResponse) apply(req *http.Request, key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != key {
+		return
+	}
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if c.lastModified != "" {
+		req.Header.Set("If-Modified-Since", c.lastModified)
+	}
+}
+
+// notModified returns the cached cidrs and their tags if the provider answered that they did not change
+func (c *cachedResponse) notModified(resp *http.Response, key string) ([]netaddr.IPPrefix, cidrTags, bool) {
+	if c == nil || resp.StatusCode != http.StatusNotModified {
+		return nil, cidrTags{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != key {
+		return nil, cidrTags{}, false
+	}
+	providerResponseCache.WithLabelValues(c.name, "hit").Inc()
+	return slices.Clone(c.cidrs), c.tags, true
+}
+
+// sameVersion returns the cached cidrs and their tags if the document has the version of the cached one, so that a
+// provider answering without a 304 is not parsed again when its document did not change
+func (c *cachedResponse) sameVersion(key, version string) ([]netaddr.IPPrefix, cidrTags, bool) {
+	if c == nil || version == "" {
+		return nil, cidrTags{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != key || c.version != version {
+		return nil, cidrTags{}, false
+	}
+	providerResponseCache.WithLabelValues(c.name, "hit").Inc()
+	return slices.Clone(c.cidrs), c.tags, true
+}
+
+// store caches the cidrs parsed from the response, if it can be requested conditionally or has a version
+func (c *cachedResponse) store(resp *http.Response, key, version string, cidrs []netaddr.IPPrefix, tags cidrTags) {
+	if c == nil {
+		return
+	}
+	providerResponseCache.WithLabelValues(c.name, "miss").Inc()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.key = key
+	c.etag = resp.Header.Get("ETag")
+	c.lastModified = resp.Header.Get("Last-Modified")
+	c.version = version
+	c.cidrs = slices.Clone(cidrs)
+	c.tags = tags
+	if c.etag == "" && c.lastModified == "" && c.version == "" {
+		c.key, c.cidrs, c.tags = "", nil, cidrTags{}
+	}
+}
+
+// fetchVersionedDocument fetches the json document of a provider which gives it a version, like the syncToken of AWS,
+// and reads its cidrs with read. The cached cidrs are returned instead while the document is not modified or has the
+// same version, so the key has to identify everything read depends on, like the url and the filters of the provider.
+func fetchVersionedDocument(ctx context.Context, httpClient *http.Client, cached *cachedResponse, url, key, versionField string,
+	read func(body []byte) ([]netaddr.IPPrefix, cidrTags, error)) ([]netaddr.IPPrefix, cidrTags, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil) //nolint:gosec
+	if err != nil {
+		return nil, cidrTags{}, fmt.Errorf("client: could not create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	cached.apply(req, key)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, cidrTags{}, unavailable("failed to make http call to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if cidrs, tags, ok := cached.notModified(resp, key); ok {
+		return cidrs, tags, nil
+	}
+
+	// these documents are downloaded as files, some of them served as application/octet-stream
+	body, err := readProviderFile(resp)
+	if err != nil {
+		return nil, cidrTags{}, err
+	}
+	version := jsoniter.Get(body, versionField).ToString()
+	if cidrs, tags, ok := cached.sameVersion(key, version); ok {
+		return cidrs, tags, nil
+	}
+	cidrs, tags, err := read(body)
+	if err != nil {
+		return nil, cidrTags{}, err
+	}
+
+	cached.store(resp, key, version, cidrs, tags)
+	return cidrs, tags, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const (
+	reasonIPGroupExpiring = "IPGroupExpiring"
+	reasonNoneExpiring    = "NoneExpiring"
+)
+
+// expiresWithin returns true if the group is still active but expires within the given window
+func expiresWithin(group beta1.InlineIPGroup, now time.Time, window time.Duration) bool {
+	return group.Expires != nil && groupActive(group, now) && !group.Expires.After(now.Add(window))
+}
+
+// expiringIPGroups returns the groups that expire within the window, sorted by the time they expire
+func expiringIPGroups(groups []beta1.InlineIPGroup, now time.Time, window time.Duration) []beta1.InlineIPGroup {
+	var expiring []beta1.InlineIPGroup
+	for _, group := range groups {
+		if expiresWithin(group, now, window) {
+			expiring = append(expiring, group)
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].Expires.Before(expiring[j].Expires)
+	})
+
+	return expiring
+}
+
+// earliest returns the earlier of the two times, a zero time is treated as never
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// requeueAfter returns the RequeueInterval, or the duration until next if that is sooner.
+// This makes sure that the whitelist changes exactly when a group expires and not up to RequeueInterval later.
+func (r *IPWhitelistConfigReconciler) requeueAfter(now, next time.Time) time.Duration {
+	if next.IsZero() || !next.After(now) {
+		return r.RequeueInterval
+	}
+	if until := next.Sub(now); until < r.RequeueInterval {
+		return until
+	}
+	return r.RequeueInterval
+}
+
+// updateExpiryStatus exports the expiry of the given IPGroups as metrics and sets the IPGroupsExpiring condition on the config.
+// An event is raised on the config whenever the set of groups nearing their expiry changes.
+func (r *IPWhitelistConfigReconciler) updateExpiryStatus(ctx context.Context, config *beta1.IPWhitelistConfig, groups []beta1.InlineIPGroup, now time.Time) error {
+	ipGroupExpiryTimestamp.Reset()
+	ipGroupExpiring.Reset()
+	for _, group := range groups {
+		if group.Expires == nil {
+			// groups without an expiry do not need any warning
+			continue
+		}
+		ipGroupExpiryTimestamp.WithLabelValues(group.Name).Set(float64(group.Expires.Unix()))
+		if expiresWithin(group, now, r.ExpiryWarningWindow) {
+			ipGroupExpiring.WithLabelValues(group.Name).Set(1)
+		} else {
+			ipGroupExpiring.WithLabelValues(group.Name).Set(0)
+		}
+	}
+
+	expiring := expiringIPGroups(groups, now, r.ExpiryWarningWindow)
+	condition := metav1.Condition{
+		Type:    beta1.ConditionIPGroupsExpiring,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonNoneExpiring,
+		Message: fmt.Sprintf("no ipGroup expires within %s", r.ExpiryWarningWindow),
+	}
+	if len(expiring) > 0 {
+		var names []string
+		for _, group := range expiring {
+			names = append(names, fmt.Sprintf("%s (%s)", group.Name, group.Expires.UTC().Format(time.RFC3339)))
+		}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonIPGroupExpiring
+		condition.Message = fmt.Sprintf("ipGroups expiring within %s: %s", r.ExpiryWarningWindow, strings.Join(names, ", "))
+	}
+
+	changed, err := r.setConfigCondition(ctx, config, condition)
+	if err != nil {
+		return err
+	}
+	if changed && condition.Status == metav1.ConditionTrue {
+		r.Recorder.Event(config, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+	return nil
+}
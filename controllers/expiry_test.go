@@ -0,0 +1,91 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("IPGroup expiry", func() {
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	group := func(name string, expires time.Time) beta1.InlineIPGroup {
+		return beta1.InlineIPGroup{Name: name, IPGroupSpec: beta1.IPGroupSpec{Expires: &metav1.Time{Time: expires}, CIDRS: []beta1.CIDR{{CIDR: "10.0.0.1/32"}}}}
+	}
+
+	Context("When checking if a group is active", func() {
+		It("should treat the exact instant of expiry as expired", func() {
+			Expect(groupActive(group("a", now.Add(time.Second)), now)).To(BeTrue())
+			Expect(groupActive(group("a", now), now)).To(BeFalse())
+			Expect(groupActive(group("a", now.Add(-time.Second)), now)).To(BeFalse())
+		})
+	})
+
+	Context("When listing the groups nearing their expiry", func() {
+		It("should only return active groups inside the window, soonest first", func() {
+			groups := []beta1.InlineIPGroup{
+				group("later", now.Add(6*24*time.Hour)),
+				group("expired", now.Add(-time.Hour)),
+				group("outside", now.Add(8*24*time.Hour)),
+				group("soon", now.Add(time.Hour)),
+			}
+			groups = append(groups, beta1.InlineIPGroup{Name: "permanent"})
+			expiring := expiringIPGroups(groups, now, 7*24*time.Hour)
+			Expect(expiring).To(HaveLen(2))
+			Expect(expiring[0].Name).To(Equal("soon"))
+			Expect(expiring[1].Name).To(Equal("later"))
+		})
+	})
+
+	Context("When computing the requeue interval", func() {
+		r := IPWhitelistConfigReconciler{RequeueInterval: time.Minute}
+
+		It("should requeue at the next change when it is sooner than the interval", func() {
+			Expect(r.requeueAfter(now, now.Add(10*time.Second))).To(Equal(10 * time.Second))
+		})
+		It("should fall back to the interval otherwise", func() {
+			Expect(r.requeueAfter(now, time.Time{})).To(Equal(time.Minute))
+			Expect(r.requeueAfter(now, now.Add(time.Hour))).To(Equal(time.Minute))
+			Expect(r.requeueAfter(now, now.Add(-time.Hour))).To(Equal(time.Minute))
+		})
+		It("should pick the earliest non zero time", func() {
+			Expect(earliest(time.Time{}, now)).To(Equal(now))
+			Expect(earliest(now, time.Time{})).To(Equal(now))
+			Expect(earliest(now.Add(time.Hour), now)).To(Equal(now))
+		})
+	})
+
+	Context("When warning about an expiring group", func() {
+		It("should only warn again once the expiry changes or the warning was cleared", func() {
+			var events raisedEvents
+			Expect(events.changed("default/web office", "2023-03-02T12:00:00Z")).To(BeTrue())
+			Expect(events.changed("default/web office", "2023-03-02T12:00:00Z")).To(BeFalse())
+			Expect(events.changed("default/api office", "2023-03-02T12:00:00Z")).To(BeTrue())
+
+			By("warning again for a new expiry")
+			Expect(events.changed("default/web office", "2023-03-03T12:00:00Z")).To(BeTrue())
+
+			By("warning again once the group was extended and expires soon again")
+			events.forget("default/web office")
+			Expect(events.changed("default/web office", "2023-03-03T12:00:00Z")).To(BeTrue())
+		})
+	})
+})
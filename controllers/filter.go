@@ -0,0 +1,173 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// cidrTags indexes the cidrs of a provider by their service and region, for the rules filtering the provider. A cidr
+// can be in several services, and the cidrs of providers without services or regions are in none.
+type cidrTags struct {
+	services map[string][]netaddr.IPPrefix
+	regions  map[string][]netaddr.IPPrefix
+}
+
+func (t *cidrTags) addService(service string, cidr netaddr.IPPrefix) {
+	if t.services == nil {
+		t.services = make(map[string][]netaddr.IPPrefix)
+	}
+	t.services[service] = append(t.services[service], cidr)
+}
+
+func (t *cidrTags) addRegion(region string, cidr netaddr.IPPrefix) {
+	if t.regions == nil {
+		t.regions = make(map[string][]netaddr.IPPrefix)
+	}
+	t.regions[region] = append(t.regions[region], cidr)
+}
+
+// defaultIPFamily is the family of the cidrs of the provider whitelisted by rules not filtering it. Cloudflare stays
+// IPv4 only, as it was before its IPv6 cidrs were fetched.
+func defaultIPFamily(provider beta1.Providers) beta1.IPFamily {
+	if provider.Type == beta1.Cloudflare {
+		return beta1.IPFamilyIPv4
+	}
+	return beta1.IPFamilyDualStack
+}
+
+// filterProviderCIDRs returns the cidrs of the provider selected by the filter of a rule. Filtering by a service or
+// region the provider has no cidrs for is an error, rather than silently whitelisting nothing of the provider.
+func filterProviderCIDRs(provider beta1.Providers, filter *beta1.ProviderFilter, cidrs []netaddr.IPPrefix, tags cidrTags) ([]netaddr.IPPrefix, error) {
+	family := defaultIPFamily(provider)
+	var services, regions []string
+	if filter != nil {
+		if filter.IPFamily != "" {
+			family = filter.IPFamily
+		}
+		services, regions = filter.Services, filter.Regions
+	}
+	inServices, err := taggedCIDRs(provider.Name, "service", tags.services, services)
+	if err != nil {
+		return nil, err
+	}
+	inRegions, err := taggedCIDRs(provider.Name, "region", tags.regions, regions)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []netaddr.IPPrefix
+	for _, cidr := range cidrs {
+		switch {
+		case family == beta1.IPFamilyIPv4 && !cidr.IP().Is4(),
+			family == beta1.IPFamilyIPv6 && !cidr.IP().Is6(),
+			inServices != nil && !inServices[cidr],
+			inRegions != nil && !inRegions[cidr]:
+			continue
+		}
+		filtered = append(filtered, cidr)
+	}
+	return filtered, nil
+}
+
+// taggedCIDRs returns the set of the cidrs tagged with any of the values, nil if there are no values to filter by
+func taggedCIDRs(provider, kind string, index map[string][]netaddr.IPPrefix, values []string) (map[netaddr.IPPrefix]bool, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	set := make(map[netaddr.IPPrefix]bool)
+	for _, value := range values {
+		cidrs, ok := index[value]
+		if !ok {
+			return nil, fmt.Errorf("provider %s has no cidrs for the %s %s, it has %v", provider, kind, value, tagNames(index))
+		}
+		for _, cidr := range cidrs {
+			set[cidr] = true
+		}
+	}
+	return set, nil
+}
+
+// tagNames returns the sorted names of the tags
+func tagNames(index map[string][]netaddr.IPPrefix) []string {
+	names := make([]string, 0, len(index))
+	for name := range index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matches returns true if the value is one of the values, or if there are no values to match
+func matches(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
+
+// documentFilter is a filter of a provider on a field of the entries of the document it fetches, with the values of
+// the field in the document
+type documentFilter struct {
+	kind   string
+	values []string
+	known  map[string]bool
+}
+
+// checkDocumentFilters returns an error naming the filter values no entry of the document has, as they are most
+// likely a typo
+func checkDocumentFilters(document string, filters ...documentFilter) error {
+	for _, filter := range filters {
+		var unknown []string
+		for _, value := range filter.values {
+			if !filter.known[value] {
+				unknown = append(unknown, value)
+			}
+		}
+		if len(unknown) > 0 {
+			return badResponse("%s %s not found in the %s, it has %s",
+				filter.kind, strings.Join(unknown, ", "), document, strings.Join(sortedKeys(filter.known), ", "))
+		}
+	}
+	return nil
+}
+
+// noDocumentMatch returns the error for filters which are each found in the document, but match no entry of it together,
+// like the services of a provider only published in other regions
+func noDocumentMatch(document string, filters ...documentFilter) error {
+	var set []string
+	for _, filter := range filters {
+		if len(filter.values) > 0 {
+			set = append(set, filter.kind+" "+strings.Join(filter.values, ", "))
+		}
+	}
+	return badResponse("no entry of the %s matches %s", document, strings.Join(set, " and "))
+}
+
+// sortedKeys returns the sorted keys of the set
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"inet.af/netaddr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("Provider filters", func() {
+	hooks := netaddr.MustParseIPPrefix("192.30.252.0/22")
+	hooksV6 := netaddr.MustParseIPPrefix("2a0a:a440::/29")
+	actions := netaddr.MustParseIPPrefix("4.148.0.0/16")
+	cidrs := []netaddr.IPPrefix{hooks, hooksV6, actions}
+	var tags cidrTags
+	tags.addService("hooks", hooks)
+	tags.addService("hooks", hooksV6)
+	tags.addService("actions", actions)
+	github := beta1.Providers{Name: "github", Type: beta1.Github}
+
+	It("should keep all the cidrs without a filter", func() {
+		filtered, err := filterProviderCIDRs(github, nil, cidrs, tags)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(Equal(cidrs))
+	})
+	It("should only keep the ipv4 cidrs of cloudflare unless asked for ipv6", func() {
+		cloudflare := beta1.Providers{Name: "cloudflare", Type: beta1.Cloudflare}
+		filtered, err := filterProviderCIDRs(cloudflare, nil, cidrs, cidrTags{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(Equal([]netaddr.IPPrefix{hooks, actions}))
+
+		filtered, err = filterProviderCIDRs(cloudflare, &beta1.ProviderFilter{IPFamily: beta1.IPFamilyIPv6}, cidrs, cidrTags{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(Equal([]netaddr.IPPrefix{hooksV6}))
+	})
+	It("should filter by service and family", func() {
+		filtered, err := filterProviderCIDRs(github, &beta1.ProviderFilter{Services: []string{"hooks"}}, cidrs, tags)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(Equal([]netaddr.IPPrefix{hooks, hooksV6}))
+
+		filtered, err = filterProviderCIDRs(github, &beta1.ProviderFilter{Services: []string{"hooks", "actions"}, IPFamily: beta1.IPFamilyIPv4}, cidrs, tags)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(Equal([]netaddr.IPPrefix{hooks, actions}))
+	})
+	It("should refuse a service or region the provider has no cidrs for", func() {
+		_, err := filterProviderCIDRs(github, &beta1.ProviderFilter{Services: []string{"pages"}}, cidrs, tags)
+		Expect(err).To(MatchError("provider github has no cidrs for the service pages, it has [actions hooks]"))
+		_, err = filterProviderCIDRs(github, &beta1.ProviderFilter{Regions: []string{"eu-west-1"}}, cidrs, tags)
+		Expect(err).To(MatchError(ContainSubstring("no cidrs for the region eu-west-1")))
+	})
+	It("should keep the tags in the ProviderSnapshot", func() {
+		ctx := context.Background()
+		testScheme := runtime.NewScheme()
+		Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+		r := &IPWhitelistConfigReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).Build(),
+			Log:      logr.Discard(),
+			Recorder: record.NewFakeRecorder(10),
+		}
+		Expect(r.saveSnapshot(ctx, github, cidrs, tags, time.Now())).To(Succeed())
+		_, loaded, loadedTags, err := r.loadSnapshot(ctx, github)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(Equal(cidrs))
+		Expect(loadedTags).To(Equal(tags))
+
+		By("refusing a snapshot whose tags do not match its hash")
+		snapshot := &beta1.ProviderSnapshot{}
+		Expect(r.Get(ctx, client.ObjectKey{Name: github.Name}, snapshot)).To(Succeed())
+		snapshot.Spec.Services["hooks"] = append(snapshot.Spec.Services["hooks"], snapshot.Spec.Services["actions"]...)
+		Expect(r.Update(ctx, snapshot)).To(Succeed())
+		_, _, _, err = r.loadSnapshot(ctx, github)
+		Expect(err).To(MatchError(ContainSubstring("does not match its hash")))
+	})
+})
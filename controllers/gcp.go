@@ -0,0 +1,114 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// gcpIPRanges is the cloud.json or goog.json of Google, the prefixes of goog.json have no service or scope
+type gcpIPRanges struct {
+	SyncToken string       `json:"syncToken"`
+	Prefixes  []gcpIPRange `json:"prefixes"`
+}
+
+// gcpIPRange is either an IPv4 or an IPv6 prefix
+type gcpIPRange struct {
+	IPv4Prefix string `json:"ipv4Prefix"`
+	IPv6Prefix string `json:"ipv6Prefix"`
+	Service    string `json:"service"`
+	Scope      string `json:"scope"`
+}
+
+func getGCPCidrs(ctx context.Context, httpClient *http.Client, cached *cachedResponse, provider beta1.GCPProvider) (_ []netaddr.IPPrefix, _ cidrTags, err error) {
+	ctx, span := tracer.Start(ctx, "getGCPCidrs", trace.WithAttributes(attribute.String("url", provider.JsonApi)))
+	defer func() { endSpan(span, err) }()
+
+	key := strings.Join([]string{
+		provider.JsonApi,
+		strings.Join(provider.Scopes, ","),
+		strings.Join(provider.Services, ","),
+	}, " ")
+	// like AWS, the syncToken changes with every publication
+	return fetchVersionedDocument(ctx, httpClient, cached, provider.JsonApi, key, "syncToken", func(body []byte) ([]netaddr.IPPrefix, cidrTags, error) {
+		var ranges gcpIPRanges
+		if err := jsoniter.Unmarshal(body, &ranges); err != nil {
+			return nil, cidrTags{}, badResponse("failed to unmarshal response body from gcp: %v", err)
+		}
+		if len(ranges.Prefixes) == 0 {
+			return nil, cidrTags{}, badResponse("gcp ip ranges response has no prefixes")
+		}
+		return ranges.filter(provider)
+	})
+}
+
+// filter returns the IPv4 and IPv6 prefixes matching the scopes and services of the provider, tagged with their
+// services and with their scopes as regions
+func (r gcpIPRanges) filter(provider beta1.GCPProvider) ([]netaddr.IPPrefix, cidrTags, error) {
+	scopes, services := map[string]bool{}, map[string]bool{}
+	for _, prefix := range r.Prefixes {
+		if prefix.Scope != "" {
+			scopes[prefix.Scope] = true
+		}
+		if prefix.Service != "" {
+			services[prefix.Service] = true
+		}
+	}
+	filters := []documentFilter{
+		{kind: "scopes", values: provider.Scopes, known: scopes},
+		{kind: "services", values: provider.Services, known: services},
+	}
+	if err := checkDocumentFilters("gcp ip ranges", filters...); err != nil {
+		return nil, cidrTags{}, err
+	}
+
+	var cidrs []netaddr.IPPrefix
+	var tags cidrTags
+	for _, prefix := range r.Prefixes {
+		if !matches(provider.Scopes, prefix.Scope) || !matches(provider.Services, prefix.Service) {
+			continue
+		}
+		ip := prefix.IPv4Prefix
+		if ip == "" {
+			ip = prefix.IPv6Prefix
+		}
+		cidr, err := netaddr.ParseIPPrefix(ip)
+		if err != nil {
+			return nil, cidrTags{}, badResponse("unable to parse ip %q: %v", ip, err)
+		}
+		cidrs = append(cidrs, cidr)
+		if prefix.Service != "" {
+			tags.addService(prefix.Service, cidr)
+		}
+		if prefix.Scope != "" {
+			tags.addRegion(prefix.Scope, cidr)
+		}
+	}
+	if len(cidrs) == 0 {
+		return nil, cidrTags{}, noDocumentMatch("gcp ip ranges", filters...)
+	}
+	return cidrs, tags, nil
+}
@@ -0,0 +1,87 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("GCP provider", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if req.URL.Path == "/goog.json" {
+				_, _ = w.Write([]byte(`{"syncToken": "1700000000001", "prefixes": [{"ipv4Prefix": "8.8.4.0/24"}, {"ipv6Prefix": "2001:4860::/32"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{
+  "syncToken": "1700000000000",
+  "prefixes": [
+    {"ipv4Prefix": "34.1.208.0/20", "service": "Google Cloud", "scope": "africa-south1"},
+    {"ipv6Prefix": "2600:1900:8000::/44", "service": "Google Cloud", "scope": "africa-south1"},
+    {"ipv4Prefix": "34.22.0.0/19", "service": "Google Cloud", "scope": "europe-west1"},
+    {"ipv4Prefix": "34.32.0.0/20", "service": "Google Cloud Storage", "scope": "us-east1"}
+  ]
+}`))
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should whitelist the IPv4 and IPv6 prefixes of the scopes of cloud.json", func() {
+		cidrs, tags, err := getGCPCidrs(ctx, http.DefaultClient, nil, beta1.GCPProvider{JsonApi: server.URL + "/cloud.json", Scopes: []string{"africa-south1"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("34.1.208.0/20"),
+			netaddr.MustParseIPPrefix("2600:1900:8000::/44"),
+		}))
+		Expect(tags.services).To(HaveKey("Google Cloud"))
+		Expect(tags.regions).To(HaveKey("africa-south1"))
+
+		By("failing on a scope not in cloud.json")
+		_, _, err = getGCPCidrs(ctx, http.DefaultClient, nil, beta1.GCPProvider{JsonApi: server.URL + "/cloud.json", Scopes: []string{"us-central1"}})
+		Expect(err).To(MatchError(ContainSubstring("scopes us-central1 not found in the gcp ip ranges, it has africa-south1, europe-west1, us-east1")))
+
+		By("failing on a scope and a service matching no prefix together")
+		_, _, err = getGCPCidrs(ctx, http.DefaultClient, nil, beta1.GCPProvider{JsonApi: server.URL + "/cloud.json", Scopes: []string{"europe-west1"}, Services: []string{"Google Cloud Storage"}})
+		Expect(err).To(MatchError(ContainSubstring("no entry of the gcp ip ranges matches scopes europe-west1 and services Google Cloud Storage")))
+	})
+	It("should whitelist all the prefixes of goog.json", func() {
+		cidrs, tags, err := getGCPCidrs(ctx, http.DefaultClient, nil, beta1.GCPProvider{JsonApi: server.URL + "/goog.json"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("8.8.4.0/24"),
+			netaddr.MustParseIPPrefix("2001:4860::/32"),
+		}))
+		Expect(tags.services).To(BeEmpty())
+
+		By("only giving a rule filtering by IPv4 the IPv4 prefixes")
+		p := beta1.Providers{Name: "google", Type: beta1.GCP}
+		Expect(filterProviderCIDRs(p, &beta1.ProviderFilter{IPFamily: beta1.IPFamilyIPv4}, cidrs, tags)).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("8.8.4.0/24")}))
+	})
+})
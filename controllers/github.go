@@ -0,0 +1,108 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+	"github.com/Moulick/ingress-whitelister/utils"
+)
+
+// githubDotcomMetaAPI is the meta api of github.com, the default jsonApi of the GitHub provider
+const githubDotcomMetaAPI = "https://api.github.com/meta"
+
+// githubMetaKeysWithoutCIDRs are the keys of the meta api which are not services with cidrs
+var githubMetaKeysWithoutCIDRs = map[string]bool{
+	"verifiable_password_authentication": true,
+	"ssh_key_fingerprints":               true,
+	"ssh_keys":                           true,
+	"installed_version":                  true,
+	"domains":                            true,
+}
+
+// githubMeta is the response of the meta api of github.com or of GitHub Enterprise Server
+type githubMeta struct {
+	// services are the cidrs of every service, like hooks or actions. GitHub Enterprise Server leaves out the services
+	// it does not have, like actions if it is not enabled.
+	services map[string][]string
+	// installedVersion is the version of GitHub Enterprise Server, empty for github.com
+	installedVersion string
+}
+
+// checkGitHubFlavor returns an error if the jsonApi cannot be of the flavor of the provider
+func checkGitHubFlavor(provider beta1.GithubProvider) error {
+	if provider.Flavor == beta1.GithubFlavorEnterprise && strings.TrimSuffix(provider.JsonApi, "/") == githubDotcomMetaAPI {
+		return fmt.Errorf("the jsonApi of GitHub Enterprise Server has to be set, like https://github.example.com/api/v3/meta")
+	}
+	return nil
+}
+
+// parseGitHubMeta parses the meta api response of the flavor. Keys which are not a list of cidrs, like ones added to
+// the api later, are not services.
+func parseGitHubMeta(body []byte, flavor beta1.GithubFlavor) (githubMeta, error) {
+	var raw map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(body, &raw); err != nil {
+		return githubMeta{}, badResponse("failed to unmarshal response body from GitHub Meta API: %v", err)
+	}
+
+	meta := githubMeta{services: make(map[string][]string)}
+	for key, value := range raw {
+		if key == "installed_version" {
+			_ = jsoniter.Unmarshal(value, &meta.installedVersion)
+		}
+		if githubMetaKeysWithoutCIDRs[key] {
+			continue
+		}
+		var cidrs []string
+		if err := jsoniter.Unmarshal(value, &cidrs); err != nil {
+			continue
+		}
+		meta.services[key] = cidrs
+	}
+
+	if flavor == beta1.GithubFlavorEnterprise && meta.installedVersion == "" {
+		return githubMeta{}, badResponse("the GitHub Meta API response has no installed_version, it is not from GitHub Enterprise Server")
+	}
+	return meta, nil
+}
+
+// selectServices returns the services to whitelist, all of them if none are given, or an error naming the services
+// missing from the response
+func (m githubMeta) selectServices(services []string) ([]string, error) {
+	names := make([]string, 0, len(m.services))
+	for name := range m.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(services) == 0 {
+		return names, nil
+	}
+	if ok, missing := utils.ArrayInArray(services, names); !ok {
+		server := "github.com"
+		if m.installedVersion != "" {
+			server = "GitHub Enterprise Server " + m.installedVersion
+		}
+		return nil, badResponse("services %s not found in the GitHub Meta API response of %s, it has %s",
+			strings.Join(missing, ", "), server, strings.Join(names, ", "))
+	}
+	return services, nil
+}
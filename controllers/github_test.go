@@ -0,0 +1,71 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("GitHub meta API", func() {
+	dotcom := []byte(`{
+		"verifiable_password_authentication": true,
+		"ssh_key_fingerprints": {"SHA256_ED25519": "+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+		"ssh_keys": ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"],
+		"hooks": ["192.30.252.0/22"],
+		"web": ["140.82.112.0/20"],
+		"domains": {"website": ["*.github.com"]}
+	}`)
+	enterprise := []byte(`{
+		"verifiable_password_authentication": true,
+		"installed_version": "3.11.2",
+		"web": ["10.20.0.0/16"],
+		"api": ["10.20.0.0/16"],
+		"domains": {"website": ["github.example.com"]}
+	}`)
+
+	It("should only take the lists of cidrs as services", func() {
+		meta, err := parseGitHubMeta(dotcom, beta1.GithubFlavorDotcom)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(meta.services).To(HaveLen(2))
+		Expect(meta.services).To(HaveKeyWithValue("hooks", []string{"192.30.252.0/22"}))
+		Expect(meta.installedVersion).To(BeEmpty())
+	})
+	It("should parse the meta API of GitHub Enterprise Server", func() {
+		meta, err := parseGitHubMeta(enterprise, beta1.GithubFlavorEnterprise)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(meta.installedVersion).To(Equal("3.11.2"))
+		services, err := meta.selectServices(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(services).To(Equal([]string{"api", "web"}))
+
+		_, err = parseGitHubMeta(dotcom, beta1.GithubFlavorEnterprise)
+		Expect(err).To(MatchError(ContainSubstring("not from GitHub Enterprise Server")))
+	})
+	It("should name the missing services", func() {
+		meta, err := parseGitHubMeta(enterprise, beta1.GithubFlavorEnterprise)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = meta.selectServices([]string{"hooks", "web", "git"})
+		Expect(err).To(MatchError("BadResponse: services hooks, git not found in the GitHub Meta API response of GitHub Enterprise Server 3.11.2, it has api, web"))
+	})
+	It("should need the jsonApi of GitHub Enterprise Server", func() {
+		Expect(checkGitHubFlavor(beta1.GithubProvider{JsonApi: githubDotcomMetaAPI, Flavor: beta1.GithubFlavorEnterprise})).ToNot(Succeed())
+		Expect(checkGitHubFlavor(beta1.GithubProvider{JsonApi: "https://github.example.com/api/v3/meta", Flavor: beta1.GithubFlavorEnterprise})).To(Succeed())
+		Expect(checkGitHubFlavor(beta1.GithubProvider{JsonApi: githubDotcomMetaAPI, Flavor: beta1.GithubFlavorDotcom})).To(Succeed())
+	})
+})
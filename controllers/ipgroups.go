@@ -0,0 +1,262 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// groupActive returns true if the group is whitelisted at the given time.
+// The group is active from the exact instant of notBefore and expired from the exact instant of expiry.
+func groupActive(group beta1.InlineIPGroup, now time.Time) bool {
+	if group.NotBefore != nil && now.Before(group.NotBefore.Time) {
+		return false
+	}
+	return group.Expires == nil || now.Before(group.Expires.Time)
+}
+
+// groupOpen returns true if the group is active and its schedule, if any, is open at the given time,
+// along with the next time this will change on its own, which is zero if it never does.
+func groupOpen(group beta1.InlineIPGroup, now time.Time) (bool, time.Time, error) {
+	if group.NotBefore != nil && now.Before(group.NotBefore.Time) {
+		return false, group.NotBefore.Time, nil
+	}
+	if !groupActive(group, now) {
+		return false, time.Time{}, nil
+	}
+
+	var next time.Time
+	if group.Expires != nil {
+		next = group.Expires.Time
+	}
+	if group.Schedule != nil {
+		open, boundary, err := scheduleOpen(group.Schedule, now)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("ipGroup %s has an invalid schedule: %w", group.Name, err)
+		}
+		return open, earliest(next, boundary), nil
+	}
+	return true, next, nil
+}
+
+// activeCIDRs returns the cidrs of the group that are whitelisted at the given time,
+// along with the next time this will change on its own, which is zero if it never does.
+// The groups included by the group are not taken into account, see resolveIPGroup for that.
+func activeCIDRs(group beta1.InlineIPGroup, now time.Time) ([]string, time.Time, error) {
+	open, next, err := groupOpen(group, now)
+	if err != nil || !open {
+		return nil, next, err
+	}
+
+	var cidrs []string
+	for _, cidr := range group.CIDRS {
+		if cidr.Expires != nil {
+			if !now.Before(cidr.Expires.Time) {
+				continue
+			}
+			next = earliest(next, cidr.Expires.Time)
+		}
+		cidrs = append(cidrs, cidr.CIDR)
+	}
+
+	return cidrs, next, nil
+}
+
+// listIPGroups returns the IPGroup objects in the cluster, sorted by name
+func listIPGroups(ctx context.Context, c client.Reader) ([]beta1.IPGroup, error) {
+	var list beta1.IPGroupList
+	if err := c.List(ctx, &list); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return list.Items, nil
+}
+
+// mergeIPGroups returns the groups defined in the config followed by the IPGroup objects.
+// A group defined in the config takes precedence over an IPGroup object of the same name.
+func mergeIPGroups(inline []beta1.InlineIPGroup, objects []beta1.IPGroup) []beta1.InlineIPGroup {
+	groups := slices.Clone(inline)
+	for _, object := range objects {
+		if !slices.ContainsFunc(inline, func(group beta1.InlineIPGroup) bool { return group.Name == object.Name }) {
+			groups = append(groups, object.Inline())
+		}
+	}
+	return groups
+}
+
+// ruleIPGroups returns the names of the groups selected by the rule, the ones named in its ipGroupSelector followed by
+// the IPGroup objects matching its ipGroupLabelSelector
+func ruleIPGroups(rule beta1.Rule, objects []beta1.IPGroup) ([]string, error) {
+	names := slices.Clone(rule.IPGroupSelector)
+	if rule.IPGroupLabelSelector == nil {
+		return names, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(rule.IPGroupLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s has an invalid ipGroupLabelSelector: %w", rule.Name, err)
+	}
+	for _, object := range objects {
+		if selector.Matches(labels.Set(object.GetLabels())) && !slices.Contains(names, object.Name) {
+			names = append(names, object.Name)
+		}
+	}
+	return names, nil
+}
+
+// indexIPGroups returns the groups by their name
+func indexIPGroups(groups []beta1.InlineIPGroup) map[string]beta1.InlineIPGroup {
+	index := make(map[string]beta1.InlineIPGroup, len(groups))
+	for _, group := range groups {
+		index[group.Name] = group
+	}
+	return index
+}
+
+// resolveIPGroup returns the whitelisted cidrs of the group and of all the groups it includes, along with the next time
+// this will change on its own. An included group is whitelisted only while both, it and the group including it, are
+// active, so every group's own expiry, notBefore and schedule apply. The includes are always walked, even for inactive
+// groups, so that cycles and missing groups are found right away and not only once a group activates.
+func resolveIPGroup(group beta1.InlineIPGroup, groups map[string]beta1.InlineIPGroup, now time.Time) ([]string, time.Time, error) {
+	return resolveIPGroupPath(group, groups, now, nil)
+}
+
+func resolveIPGroupPath(group beta1.InlineIPGroup, groups map[string]beta1.InlineIPGroup, now time.Time, path []string) ([]string, time.Time, error) {
+	if slices.Contains(path, group.Name) {
+		return nil, time.Time{}, fmt.Errorf("ipGroup cycle detected: %s -> %s", strings.Join(path, " -> "), group.Name)
+	}
+	path = append(path, group.Name)
+
+	cidrs, next, err := activeCIDRs(group, now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	open, _, err := groupOpen(group, now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	for _, name := range group.IncludeGroups {
+		included, ok := groups[name]
+		if !ok {
+			return nil, time.Time{}, fmt.Errorf("ipGroup %s includes the ipGroup %s which does not exist", group.Name, name)
+		}
+		includedCIDRs, includedNext, err := resolveIPGroupPath(included, groups, now, path)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if open {
+			cidrs = append(cidrs, includedCIDRs...)
+			next = earliest(next, includedNext)
+		}
+	}
+
+	return cidrs, next, nil
+}
+
+// includesIPGroup returns true if the group is the named group or includes it, directly or through other groups
+func includesIPGroup(group beta1.InlineIPGroup, name string, groups map[string]beta1.InlineIPGroup, seen map[string]bool) bool {
+	if group.Name == name {
+		return true
+	}
+	if seen[group.Name] {
+		return false
+	}
+	seen[group.Name] = true
+	for _, include := range group.IncludeGroups {
+		if included, ok := groups[include]; ok && includesIPGroup(included, name, groups, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleUsesIPGroup returns true if the rule selects the named group, or a group including it
+func ruleUsesIPGroup(rule beta1.Rule, name string, objects []beta1.IPGroup, groups map[string]beta1.InlineIPGroup) (bool, error) {
+	names, err := ruleIPGroups(rule, objects)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(names, func(selected string) bool {
+		group, ok := groups[selected]
+		return ok && includesIPGroup(group, name, groups, map[string]bool{})
+	}), nil
+}
+
+// ingressesForIPGroup maps an IPGroup object to the ingresses matching the rules that use it, directly or through
+// other groups including it, so that changes to the object are applied right away
+func (r *IPWhitelistConfigReconciler) ingressesForIPGroup(ctx context.Context, obj client.Object) []reconcile.Request {
+	logo := r.Log.WithValues("ipGroup", obj.GetName())
+
+	config, err := r.getIPWhitelistConfig(ctx)
+	if err != nil {
+		logo.Error(err, "failed to get the IPWhitelistConfig")
+		return nil
+	}
+	objects, err := listIPGroups(ctx, r)
+	if err != nil {
+		logo.Error(err, "failed to list the IPGroups")
+		return nil
+	}
+	// the object is gone from the list already when it was deleted, but the ingresses using it still need an update
+	if ipGroup, ok := obj.(*beta1.IPGroup); ok && !slices.ContainsFunc(objects, func(o beta1.IPGroup) bool { return o.Name == ipGroup.Name }) {
+		objects = append(objects, *ipGroup)
+	}
+	groups := indexIPGroups(mergeIPGroups(config.Spec.IPGroups, objects))
+
+	var selectors []labels.Selector
+	for _, rule := range config.Spec.Rules {
+		uses, err := ruleUsesIPGroup(rule, obj.GetName(), objects, groups)
+		if err != nil {
+			logo.Error(err, "failed to select the ipGroups of the rule", "rule", rule.Name)
+			continue
+		}
+		if !uses {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(rule.Selector)
+		if err != nil {
+			logo.Error(err, "failed to convert the labelSelector to selector", "rule", rule.Name)
+			continue
+		}
+		selectors = append(selectors, selector)
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+
+	var ingresses knet.IngressList
+	if err := r.List(ctx, &ingresses); err != nil {
+		logo.Error(err, "failed to list the ingresses")
+		return nil
+	}
+	return ingressRequests(ingresses.Items, selectors)
+}
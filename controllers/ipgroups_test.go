@@ -0,0 +1,188 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("IPGroup resolution", func() {
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	at := func(t time.Time) *metav1.Time {
+		return &metav1.Time{Time: t}
+	}
+
+	Context("When reading the cidrs of a group", func() {
+		It("should accept both the plain string and the object form", func() {
+			group := beta1.InlineIPGroup{}
+			Expect(json.Unmarshal([]byte(`{
+				"name": "office",
+				"cidrs": ["10.0.0.0/8", {"cidr": "192.168.1.1/32", "expires": "2023-03-02T00:00:00Z", "ticket": "OPS-1"}]
+			}`), &group)).To(Succeed())
+			Expect(group.CIDRS).To(HaveLen(2))
+			Expect(group.CIDRS[0]).To(Equal(beta1.CIDR{CIDR: "10.0.0.0/8"}))
+			Expect(group.CIDRS[1].CIDR).To(Equal("192.168.1.1/32"))
+			Expect(group.CIDRS[1].Ticket).To(Equal("OPS-1"))
+			Expect(group.CIDRS[1].Expires.Time).To(BeTemporally("==", time.Date(2023, time.March, 2, 0, 0, 0, 0, time.UTC)))
+
+			By("writing entries with only a cidr back as plain strings")
+			out, err := json.Marshal(group.CIDRS)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(out)).To(HavePrefix(`["10.0.0.0/8",{"cidr":"192.168.1.1/32"`))
+		})
+		It("should refuse entries with an unknown key or without a cidr", func() {
+			group := beta1.InlineIPGroup{}
+			Expect(json.Unmarshal([]byte(`{"name": "office", "cidrs": [{"cidr": "192.168.1.1/32", "expiers": "2023-03-02T00:00:00Z"}]}`), &group)).
+				To(MatchError(ContainSubstring(`unknown field "expiers"`)))
+			Expect(json.Unmarshal([]byte(`{"name": "office", "cidrs": [{"expires": "2023-03-02T00:00:00Z"}]}`), &group)).
+				To(MatchError(ContainSubstring("the cidr of the entry is missing")))
+			Expect(json.Unmarshal([]byte(`{"name": "office", "cidrs": [""]}`), &group)).
+				To(MatchError(ContainSubstring("the cidr of the entry is missing")))
+			Expect(json.Unmarshal([]byte(`{"name": "office", "cidrs": [1234]}`), &group)).To(HaveOccurred())
+		})
+	})
+
+	Context("When computing the active cidrs", func() {
+		It("should keep groups without an expiry active forever", func() {
+			cidrs, next, err := activeCIDRs(beta1.InlineIPGroup{IPGroupSpec: beta1.IPGroupSpec{CIDRS: []beta1.CIDR{{CIDR: "10.0.0.0/8"}}}}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"10.0.0.0/8"}))
+			Expect(next.IsZero()).To(BeTrue())
+		})
+		It("should not return anything before notBefore and change exactly at notBefore", func() {
+			group := beta1.InlineIPGroup{IPGroupSpec: beta1.IPGroupSpec{NotBefore: at(now.Add(time.Hour)), CIDRS: []beta1.CIDR{{CIDR: "10.0.0.0/8"}}}}
+			cidrs, next, err := activeCIDRs(group, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(BeEmpty())
+			Expect(next).To(Equal(now.Add(time.Hour)))
+
+			cidrs, _, err = activeCIDRs(group, now.Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"10.0.0.0/8"}))
+		})
+		It("should drop expired cidrs and change at the earliest expiry", func() {
+			group := beta1.InlineIPGroup{
+				IPGroupSpec: beta1.IPGroupSpec{
+					Expires: at(now.Add(3 * time.Hour)),
+					CIDRS: []beta1.CIDR{
+						{CIDR: "10.0.0.0/8"},
+						{CIDR: "10.0.0.1/32", Expires: at(now)},
+						{CIDR: "10.0.0.2/32", Expires: at(now.Add(time.Hour))},
+					},
+				},
+			}
+			cidrs, next, err := activeCIDRs(group, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"10.0.0.0/8", "10.0.0.2/32"}))
+			Expect(next).To(Equal(now.Add(time.Hour)))
+		})
+		It("should not return anything once the group expired", func() {
+			group := beta1.InlineIPGroup{IPGroupSpec: beta1.IPGroupSpec{Expires: at(now), CIDRS: []beta1.CIDR{{CIDR: "10.0.0.0/8"}}}}
+			cidrs, next, err := activeCIDRs(group, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(BeEmpty())
+			Expect(next.IsZero()).To(BeTrue())
+		})
+	})
+
+	Context("When a group includes other groups", func() {
+		office := beta1.InlineIPGroup{Name: "office-berlin", IPGroupSpec: beta1.IPGroupSpec{CIDRS: []beta1.CIDR{{CIDR: "10.1.0.0/16"}}}}
+		vpn := beta1.InlineIPGroup{Name: "vpn-eu", IPGroupSpec: beta1.IPGroupSpec{Expires: at(now.Add(time.Hour)), CIDRS: []beta1.CIDR{{CIDR: "10.2.0.0/16"}}}}
+		expired := beta1.InlineIPGroup{Name: "office-old", IPGroupSpec: beta1.IPGroupSpec{Expires: at(now), CIDRS: []beta1.CIDR{{CIDR: "10.3.0.0/16"}}}}
+		allOffices := beta1.InlineIPGroup{Name: "all-offices", IPGroupSpec: beta1.IPGroupSpec{IncludeGroups: []string{"office-berlin", "office-old"}}}
+		admin := beta1.InlineIPGroup{
+			Name: "admin",
+			IPGroupSpec: beta1.IPGroupSpec{
+				IncludeGroups: []string{"all-offices", "vpn-eu"},
+				CIDRS:         []beta1.CIDR{{CIDR: "192.168.0.1/32"}},
+			},
+		}
+		groups := indexIPGroups([]beta1.InlineIPGroup{office, vpn, expired, allOffices, admin})
+
+		It("should resolve the cidrs of the whole tree and apply every group's own expiry", func() {
+			cidrs, next, err := resolveIPGroup(admin, groups, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(ConsistOf("192.168.0.1/32", "10.1.0.0/16", "10.2.0.0/16"))
+			Expect(next).To(Equal(now.Add(time.Hour)))
+		})
+		It("should not include anything while the including group is not active", func() {
+			notYet := admin
+			notYet.NotBefore = at(now.Add(2 * time.Hour))
+			cidrs, next, err := resolveIPGroup(notYet, groups, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(BeEmpty())
+			Expect(next).To(Equal(now.Add(2 * time.Hour)))
+		})
+		It("should detect cycles", func() {
+			a := beta1.InlineIPGroup{Name: "a", IPGroupSpec: beta1.IPGroupSpec{IncludeGroups: []string{"b"}}}
+			b := beta1.InlineIPGroup{Name: "b", IPGroupSpec: beta1.IPGroupSpec{IncludeGroups: []string{"a"}}}
+			_, _, err := resolveIPGroup(a, indexIPGroups([]beta1.InlineIPGroup{a, b}), now)
+			Expect(err).To(MatchError(ContainSubstring("a -> b -> a")))
+		})
+		It("should fail on groups that do not exist", func() {
+			missing := beta1.InlineIPGroup{Name: "a", IPGroupSpec: beta1.IPGroupSpec{IncludeGroups: []string{"typo"}}}
+			_, _, err := resolveIPGroup(missing, groups, now)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When groups are also defined as IPGroup objects", func() {
+		object := func(name string, labels map[string]string, cidr string) beta1.IPGroup {
+			return beta1.IPGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+				Spec:       beta1.IPGroupSpec{CIDRS: []beta1.CIDR{{CIDR: cidr}}},
+			}
+		}
+		objects := []beta1.IPGroup{
+			object("office-berlin", map[string]string{"team": "it"}, "10.9.0.0/16"),
+			object("office-nyc", map[string]string{"team": "it"}, "10.8.0.0/16"),
+			object("payments-vpn", map[string]string{"team": "payments"}, "10.7.0.0/16"),
+		}
+		inline := []beta1.InlineIPGroup{
+			{Name: "office-berlin", IPGroupSpec: beta1.IPGroupSpec{CIDRS: []beta1.CIDR{{CIDR: "10.1.0.0/16"}}}},
+			{Name: "all-offices", IPGroupSpec: beta1.IPGroupSpec{IncludeGroups: []string{"office-nyc"}}},
+		}
+
+		It("should prefer the groups defined in the config over objects of the same name", func() {
+			groups := indexIPGroups(mergeIPGroups(inline, objects))
+			Expect(groups).To(HaveLen(4))
+			Expect(groups["office-berlin"].CIDRS).To(Equal([]beta1.CIDR{{CIDR: "10.1.0.0/16"}}))
+			Expect(groups["office-nyc"].CIDRS).To(Equal([]beta1.CIDR{{CIDR: "10.8.0.0/16"}}))
+		})
+		It("should select the objects by name and by label", func() {
+			rule := beta1.Rule{
+				Name:                 "it",
+				IPGroupSelector:      []string{"payments-vpn"},
+				IPGroupLabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "it"}},
+			}
+			names, err := ruleIPGroups(rule, objects)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"payments-vpn", "office-berlin", "office-nyc"}))
+		})
+		It("should find the groups including an object", func() {
+			groups := indexIPGroups(mergeIPGroups(inline, objects))
+			Expect(includesIPGroup(groups["all-offices"], "office-nyc", groups, map[string]bool{})).To(BeTrue())
+			Expect(includesIPGroup(groups["all-offices"], "payments-vpn", groups, map[string]bool{})).To(BeFalse())
+		})
+	})
+})
@@ -20,17 +20,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/cloudflare/cloudflare-go"
-	"github.com/corbaltcode/go-akamai"
 	"github.com/corbaltcode/go-akamai/siteshield"
 	"github.com/go-logr/logr"
 	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"inet.af/netaddr"
 
 	corev1 "k8s.io/api/core/v1"
@@ -39,10 +40,10 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-
-	"github.com/Moulick/ingress-whitelister/utils"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
 )
@@ -54,6 +55,11 @@ type ProviderString string
 const (
 	// requeueInterval    = 2 * time.Minute
 	errRequeueInterval = 5 * time.Second
+	// errRequeueIntervalAkamai is longer as the api of akamai is slow
+	errRequeueIntervalAkamai = 15 * time.Second
+	// denyAllWhitelist is the whitelist of an ingress whose rule matched but has no cidrs right now, like outside the
+	// schedule of its only ipGroup. Removing the annotation would open up the ingress to everyone instead.
+	denyAllWhitelist = "0.0.0.0/32"
 )
 
 // IPWhitelistConfigReconciler reconciles a IPWhitelistConfig object
@@ -63,6 +69,22 @@ type IPWhitelistConfigReconciler struct {
 	IPWhitelistConfig string
 	RequeueInterval   time.Duration
 	Log               logr.Logger
+	Recorder          record.EventRecorder
+	// ExpiryWarningWindow is how long before their expiry IPGroups are reported as expiring
+	ExpiryWarningWindow time.Duration
+
+	// providers keeps the last fetch of every provider, for the providers using the UseLastKnownGood failurePolicy
+	providers providerCache
+	// httpClients keeps the http client of every provider with an httpClient of its own
+	httpClients providerClients
+	// responses keeps the last response of every http provider, to send conditional requests
+	responses responseCache
+	// rateLimits keeps the rate limit of every provider reporting one
+	rateLimits rateLimits
+	// siteShieldMaps keeps the last fetched map of every akamai provider, for acknowledging its proposal
+	siteShieldMaps siteShieldMaps
+	// events keeps the warnings raised on the ingresses and the config, so they are not raised on every reconcile
+	events raisedEvents
 }
 
 func (p ProviderString) String() string {
@@ -72,17 +94,27 @@ func (p ProviderString) String() string {
 // +kubebuilder:rbac:groups=ingress.security.moulick,resources=ipwhitelistconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ingress.security.moulick,resources=ipwhitelistconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=ingress.security.moulick,resources=ipwhitelistconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=ingress.security.moulick,resources=ipgroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ingress.security.moulick,resources=namespaceipgroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ingress.security.moulick,resources=providersnapshots,verbs=get;list;watch;create;update;patch
 
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;update;patch
 
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// the Secrets and ConfigMaps referenced by the providers are read with config/rbac/secret_reader_role.yaml
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.10.0/pkg/reconcile
 // Interesting thing to note here is that this Reconcile is triggered for Ingress Objects and not IPWhitelistConfig
-func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
 	var finalWhiteList []string
 
+	ctx, span := tracer.Start(ctx, "Reconcile", trace.WithAttributes(attribute.String("ingress", req.String())))
+	defer func() { endSpan(span, err) }()
+
 	logo := r.Log.WithValues("ingress", req.NamespacedName)
 
 	// check if IPWhitelistConfig is specified
@@ -98,6 +130,27 @@ func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{RequeueAfter: errRequeueInterval}, err
 	}
 
+	// the groups can also be defined as IPGroup objects, next to the ones in the config
+	ipGroupObjects, err := listIPGroups(ctx, r)
+	if err != nil {
+		logo.Error(err, "failed to list the IPGroups")
+		return ctrl.Result{RequeueAfter: errRequeueInterval}, err
+	}
+	allIPGroups := mergeIPGroups(ipWhitelistConfig.Spec.IPGroups, ipGroupObjects)
+	// rules violating the policy are not applied at all, which is reported in the status of the config
+	violations := policyViolations(ipWhitelistConfig, ipGroupObjects)
+	if err := r.updatePolicyStatus(ctx, ipWhitelistConfig, violations); err != nil {
+		logo.Error(err, "failed to update the policy status of the IPWhitelistConfig")
+	}
+
+	now := time.Now()
+	// the time at which the whitelist will change next on its own, like an ipGroup expiring
+	var nextChange time.Time
+	// failing to update the status should not stop the ingress from getting its whitelist
+	if err := r.updateExpiryStatus(ctx, ipWhitelistConfig, allIPGroups, now); err != nil {
+		logo.Error(err, "failed to update the expiry status of the IPWhitelistConfig")
+	}
+
 	// Fetch the IPWhitelistConfig ing
 	ing := &knet.Ingress{}
 	err = r.Get(ctx, req.NamespacedName, ing)
@@ -108,6 +161,14 @@ func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		// we can ignore not found error as requing the ingress will not help anyways
 		return ctrl.Result{}, nil
 	}
+	ipGroups := indexIPGroups(allIPGroups)
+	// ruleMatched tells a rule without any cidrs right now apart from no rule matching at all
+	var ruleMatched bool
+	// providerSkipped is set when a provider of the matched rule failed and was left out by its Skip failurePolicy
+	var providerSkipped bool
+	// the ingress is warned once about the rule refused in its namespace, not on every reconcile
+	ruleRefusedKey := fmt.Sprintf("%s/%s", reasonRuleRefused, req.NamespacedName)
+	var ruleRefused bool
 	// loop over all the rules and check if the labels match
 	for _, rule := range ipWhitelistConfig.Spec.Rules {
 		selector, err := metav1.LabelSelectorAsSelector(rule.Selector)
@@ -117,66 +178,115 @@ func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		}
 		// check if the ingress matches the defined selectors
 		if selector.Matches(labels.Set(ing.GetLabels())) {
+			// the labels are set by the owner of the ingress, so check that its namespace may use the rule at all
+			allowed, err := r.namespaceAllowed(ctx, rule.AllowedNamespaces, ing.Namespace)
+			if err != nil {
+				logo.Error(err, "failed to check if the namespace is allowed to use the rule", "rule", rule.Name)
+				return ctrl.Result{RequeueAfter: errRequeueInterval}, err
+			}
+			if !allowed {
+				// removing the whitelist would open up the ingress to everyone, so it is closed instead
+				logo.Info("Ingress matches the rule but its namespace is not allowed to use it, denying all", "rule", rule.Name)
+				ruleRefused = true
+				if r.events.changed(ruleRefusedKey, rule.Name) {
+					r.Recorder.Eventf(ing, corev1.EventTypeWarning, reasonRuleRefused, "rule %s is not allowed in namespace %s", rule.Name, ing.Namespace)
+				}
+				ruleMatched = true
+				break
+			}
+			if violation := violations[rule.Name]; violation != nil {
+				// removing the whitelist would open up the ingress, so it is left as it is until the rule is fixed
+				logo.Info("Ingress matches the rule but the rule violates the policy, leaving the ingress as it is", "rule", rule.Name, "violation", violation.Error())
+				r.Recorder.Eventf(ing, corev1.EventTypeWarning, reasonPolicyViolated, "rule %s is not applied: %s", rule.Name, violation)
+				return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+			}
 			logo.Info("Ingress matches the rule", "rule", rule.Name)
-			// Loop through the list of IPGroupSelector
-			for _, ipGroup := range rule.IPGroupSelector {
-				// Loop and check if the ipSets has any
-				for _, group := range ipWhitelistConfig.Spec.IPGroups {
-					if group.Name == ipGroup {
-						now := metav1.Now()
-						if !group.Expires.Before(&now) {
-							logo.Info("ipGroup matched, added", "ipGroup", ipGroup)
-							finalWhiteList = append(finalWhiteList, group.CIDRS...)
-						} else {
-							logo.Info("ipGroup matched but expired", "ipGroup", ipGroup, "expiry", group.Expires.Format(time.RFC1123))
-						}
-						// as soon as we find the matching group, we can break out of the loop
-						break
+			ruleMatched = true
+			ipGroupNames, err := ruleIPGroups(rule, ipGroupObjects)
+			if err != nil {
+				logo.Error(err, "failed to select the ipGroups")
+				return ctrl.Result{}, err
+			}
+			// Loop through the selected groups
+			for _, ipGroup := range ipGroupNames {
+				group, ok := ipGroups[ipGroup]
+				if !ok {
+					logo.Info("ipGroup not found, skipping", "ipGroup", ipGroup)
+					continue
+				}
+				cidrs, next, err := resolveIPGroup(group, ipGroups, now)
+				if err != nil {
+					logo.Error(err, "failed to resolve the ipGroup", "ipGroup", ipGroup)
+					return ctrl.Result{RequeueAfter: errRequeueInterval}, err
+				}
+				// requeue exactly when the group or one of its cidrs activates or expires, or a window of its
+				// schedule opens or closes, so the access starts and ends on time
+				nextChange = earliest(nextChange, next)
+				switch {
+				case group.NotBefore != nil && now.Before(group.NotBefore.Time):
+					logo.Info("ipGroup matched but not active yet", "ipGroup", ipGroup, "notBefore", group.NotBefore.Format(time.RFC1123))
+				case !groupActive(group, now):
+					logo.Info("ipGroup matched but expired", "ipGroup", ipGroup, "expiry", group.Expires.Format(time.RFC1123))
+				case group.Schedule != nil && len(cidrs) == 0:
+					logo.Info("ipGroup matched but outside of its schedule", "ipGroup", ipGroup, "nextChange", next.Format(time.RFC1123))
+				default:
+					logo.Info("ipGroup matched, added", "ipGroup", ipGroup)
+					finalWhiteList = append(finalWhiteList, cidrs...)
+					// the ingress is warned once per expiry of the group, not on every reconcile until it expires
+					eventKey := fmt.Sprintf("%s/%s %s", reasonIPGroupExpiring, req.NamespacedName, group.Name)
+					if !expiresWithin(group, now, r.ExpiryWarningWindow) {
+						r.events.forget(eventKey)
+					} else if r.events.changed(eventKey, group.Expires.UTC().Format(time.RFC3339)) {
+						r.Recorder.Eventf(ing, corev1.EventTypeWarning, reasonIPGroupExpiring, "ipGroup %s expires at %s", group.Name, group.Expires.UTC().Format(time.RFC3339))
 					}
 				}
 			}
 
+			if rule.NamespaceIPGroups != nil {
+				cidrs, next, err := r.namespaceCIDRs(ctx, ing, rule.NamespaceIPGroups, ipWhitelistConfig.Spec.Policy, now)
+				if err != nil {
+					logo.Error(err, "failed to get the cidrs of the namespaceIPGroups")
+					return ctrl.Result{RequeueAfter: errRequeueInterval}, err
+				}
+				// the namespaceIPGroups are only known now, so the maxAddresses of the rule is checked again with them
+				if violation := checkRulePolicy(nil, rule, append(slices.Clone(finalWhiteList), cidrs...)); violation != nil {
+					logo.Info("Ingress matches the rule but the rule violates the policy, leaving the ingress as it is", "rule", rule.Name, "violation", violation.Error())
+					r.Recorder.Eventf(ing, corev1.EventTypeWarning, reasonPolicyViolated, "rule %s is not applied: %s", rule.Name, violation)
+					return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+				}
+				nextChange = earliest(nextChange, next)
+				finalWhiteList = append(finalWhiteList, cidrs...)
+			}
+
 			for _, x := range rule.ProviderSelector {
 				for _, y := range ipWhitelistConfig.Spec.Providers {
 					if x.Name == y.Name {
-						switch y.Type {
-						case beta1.Cloudflare:
-							logo.Info("Provider matched", "provider", y.Name)
-							ips, err := getCloudFlareCidrs(y.Cloudflare)
-							if err != nil {
-								logo.Error(err, fmt.Sprintf("failed to get cidrs from %s", y.Name))
-								return ctrl.Result{RequeueAfter: errRequeueInterval}, err
-							}
-							for _, ip := range ips {
-								finalWhiteList = append(finalWhiteList, ip.String())
-							}
-
-						case beta1.Github:
-							logo.Info("Provider matched", "provider", y.Name)
-							ips, err := getGitHubCidrs(y.Github)
-							if err != nil {
-								logo.Error(err, fmt.Sprintf("failed to get cidrs from %s", y.Name))
-								return ctrl.Result{RequeueAfter: errRequeueInterval}, err
-							}
-							for _, ip := range ips {
-								finalWhiteList = append(finalWhiteList, ip.String())
+						logo.Info("Provider matched", "provider", y.Name)
+						ips, err := r.providerCIDRs(ctx, ipWhitelistConfig, ing, y, x.Filter)
+						// the status is updated either way, so that a provider recovering is reported right away
+						if err := r.updateProviderStatus(ctx, ipWhitelistConfig); err != nil {
+							logo.Error(err, "failed to update the provider status of the IPWhitelistConfig")
+						}
+						if errors.Is(err, errProviderSkipped) {
+							// the rest of the rule is still applied
+							providerSkipped = true
+							continue
+						}
+						if err != nil {
+							logo.Error(err, fmt.Sprintf("failed to get cidrs from %s", y.Name))
+							var rateLimited *rateLimitedError
+							if errors.As(err, &rateLimited) {
+								// requesting the provider again before its rate limit resets would only fail the same
+								return ctrl.Result{RequeueAfter: time.Until(rateLimited.reset)}, nil
 							}
-
-						case beta1.Akamai:
-							logo.Info("Provider matched", "provider", y.Name)
-							ips, err := r.getAkamaiCidrs(ctx, y.Akamai)
-							if err != nil {
-								logo.Error(err, fmt.Sprintf("failed to get cidrs from %s", y.Name))
+							if y.Type == beta1.Akamai {
 								// if we fail to get CIDRs from akami, slow down the reconciliation loop, the api call to akamai is slow
-								return ctrl.Result{RequeueAfter: 15 * time.Second}, err
-							}
-							for _, ip := range ips {
-								finalWhiteList = append(finalWhiteList, ip.String())
+								return ctrl.Result{RequeueAfter: errRequeueIntervalAkamai}, err
 							}
-
-						case beta1.Fastly:
-							logo.Info("Provider matched", "provider", y.Name)
-							logo.Info("fastly provider not implemented yet")
+							return ctrl.Result{RequeueAfter: errRequeueInterval}, err
+						}
+						for _, ip := range ips {
+							finalWhiteList = append(finalWhiteList, ip.String())
 						}
 					}
 				}
@@ -187,26 +297,39 @@ func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 
 	}
 
+	if !ruleRefused {
+		r.events.forget(ruleRefusedKey)
+	}
+
+	if _, ok := ing.Annotations[ipWhitelistConfig.Spec.WhitelistAnnotation]; ok && len(finalWhiteList) == 0 && providerSkipped {
+		// the skipped providers were the only source of the rule, so the whitelist is left as it is until they recover
+		logo.Info("Rule matched but only its skipped providers have cidrs, leaving the ingress as it is")
+		return ctrl.Result{RequeueAfter: r.requeueAfter(now, nextChange)}, nil
+	}
+	if len(finalWhiteList) == 0 && ruleMatched {
+		logo.Info("Rule matched but has no cidrs right now, denying all")
+		finalWhiteList = []string{denyAllWhitelist}
+	}
 	// if the finalWhiteList is empty, then no rule matched, so we can try to remove the annotation
 	if len(finalWhiteList) == 0 {
 		logo.Info("No rule matched, skipping and/or cleaning up")
 		if ing.Annotations == nil {
 			// if the finalWhiteList is empty and no rule matched, don't need to do anything
-			return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+			return ctrl.Result{RequeueAfter: r.requeueAfter(now, nextChange)}, nil
 		}
 		// if the annotations are not nil, we can try to delete the annotation
 		var deleted bool
 		if ing.Annotations, deleted = deleteAnnotation(ing.Annotations, ipWhitelistConfig.Spec.WhitelistAnnotation); deleted {
-			if err = r.Update(ctx, ing); err != nil {
+			if err = r.updateIngress(ctx, ing); err != nil {
 				logo.Error(err, "failed to update the ingress")
 				return ctrl.Result{RequeueAfter: errRequeueInterval}, err
 			}
 			logo.Info("removed annotation from ingress")
 
-			return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+			return ctrl.Result{RequeueAfter: r.requeueAfter(now, nextChange)}, nil
 		}
 		// there was nothing to delete or update, we are done here
-		return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+		return ctrl.Result{RequeueAfter: r.requeueAfter(now, nextChange)}, nil
 	}
 	// Here we have a whitelist and we might need to update the annotations
 	// sort the finalWhiteList
@@ -218,16 +341,16 @@ func (r *IPWhitelistConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	if !annotationAlreadyEqual(ing.Annotations, ipWhitelistConfig.Spec.WhitelistAnnotation, finalWhiteListString) {
 		// if above condition is false, we need to update the annotation
 		ing.Annotations[ipWhitelistConfig.Spec.WhitelistAnnotation] = finalWhiteListString
-		if err = r.Update(ctx, ing); err != nil {
+		if err = r.updateIngress(ctx, ing); err != nil {
 			logo.Error(err, "failed to update the ingress")
 			return ctrl.Result{RequeueAfter: errRequeueInterval}, err
 		}
 		logo.Info("updated the ingress")
-		return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+		return ctrl.Result{RequeueAfter: r.requeueAfter(now, nextChange)}, nil
 	}
 
 	logo.Info("ingress already up-to-date")
-	return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+	return ctrl.Result{RequeueAfter: r.requeueAfter(now, nextChange)}, nil
 }
 
 // getIPWhitelistConfig retrieves the ruleSet configuration.
@@ -241,6 +364,16 @@ func (r *IPWhitelistConfigReconciler) getIPWhitelistConfig(ctx context.Context)
 	return &iPWhitelistConfig, nil
 }
 
+// updateIngress updates the ingress in the cluster, wrapped in a span to see how long the api server takes
+func (r *IPWhitelistConfigReconciler) updateIngress(ctx context.Context, ing *knet.Ingress) (err error) {
+	ctx, span := tracer.Start(ctx, "updateIngress", trace.WithAttributes(
+		attribute.String("ingress", client.ObjectKeyFromObject(ing).String()),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return r.Update(ctx, ing)
+}
+
 // Return a list of unique and sorted list of strings from input
 func uniqueSorted(slice []string) []string {
 	keys := make(map[string]bool)
@@ -283,10 +416,19 @@ func (r *IPWhitelistConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		// For(&beta1.IPWhitelistConfig{}).
 		For(&knet.Ingress{}).
+		Watches(&beta1.IPGroup{}, handler.EnqueueRequestsFromMapFunc(r.ingressesForIPGroup)).
+		Watches(&beta1.NamespaceIPGroup{}, handler.EnqueueRequestsFromMapFunc(r.ingressesForNamespaceIPGroup)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.ingressesForNamespace)).
 		Complete(r)
 }
 
-func (r *IPWhitelistConfigReconciler) readSecretKey(ctx context.Context, secretRef *beta1.SecretKeySelector) (string, error) {
+func (r *IPWhitelistConfigReconciler) readSecretKey(ctx context.Context, secretRef *beta1.SecretKeySelector) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "readSecretKey", trace.WithAttributes(
+		attribute.String("secret", secretRef.Secret.Namespace+"/"+secretRef.Secret.Name),
+		attribute.String("key", secretRef.Key),
+	))
+	defer func() { endSpan(span, err) }()
+
 	secret := &corev1.Secret{}
 	if err := r.Get(
 		ctx,
@@ -300,7 +442,7 @@ func (r *IPWhitelistConfigReconciler) readSecretKey(ctx context.Context, secretR
 	}
 	val, ok := secret.Data[secretRef.Key]
 	if !ok {
-		err := fmt.Errorf("secret missing key")
+		err = fmt.Errorf("secret missing key")
 		r.Log.Error(err, "missing key in secret", "namespacedname", secret.Namespace+"/"+secret.Name, "key", secretRef.Key)
 		return "", err
 	}
@@ -309,24 +451,42 @@ func (r *IPWhitelistConfigReconciler) readSecretKey(ctx context.Context, secretR
 
 // getCloudFlareCidrs returns the CIDRs for the given CloudFlare provider.
 // This code is copied from https://github.com/cloudflare/cloudflare-go/blob/master/ips.go and modified to take given URL as input.
-func getCloudFlareCidrs(provider beta1.CloudflareProvider) ([]netaddr.IPPrefix, error) {
+func getCloudFlareCidrs(ctx context.Context, httpClient *http.Client, cached *cachedResponse, provider beta1.CloudflareProvider) (_ []netaddr.IPPrefix, err error) {
 	var cloudFlareIps []netaddr.IPPrefix
 
+	ctx, span := tracer.Start(ctx, "getCloudFlareCidrs", trace.WithAttributes(attribute.String("url", provider.JsonApi)))
+	defer func() { endSpan(span, err) }()
+
 	uri := provider.JsonApi
-	resp, err := http.Get(uri) //nolint:gosec
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("client: could not create request: %v", err)
+	}
+	cached.apply(req, uri)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make http call to cloudflare: %v", err)
+		return nil, unavailable("failed to make http call to cloudflare: %w", err)
 	}
 	defer resp.Body.Close()
+	if cidrs, _, ok := cached.notModified(resp, uri); ok {
+		return cidrs, nil
+	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readProviderResponse(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, err
 	}
 	var cresp cloudflare.IPsResponse
 	err = jsoniter.Unmarshal(body, &cresp)
 	if err != nil {
-		return nil, err
+		return nil, badResponse("failed to unmarshal response body from cloudflare: %v", err)
+	}
+	// cloudflare reports errors in the envelope of the response, even with a 200
+	if !cresp.Success || len(cresp.Errors) > 0 {
+		return nil, badResponse("cloudflare api returned success %t with errors %v", cresp.Success, cresp.Errors)
+	}
+	if len(cresp.Result.IPv4CIDRs) == 0 {
+		return nil, badResponse("cloudflare api returned no ipv4 cidrs")
 	}
 
 	var ips cloudflare.IPRanges
@@ -341,118 +501,128 @@ func getCloudFlareCidrs(provider beta1.CloudflareProvider) ([]netaddr.IPPrefix,
 		}
 	}
 
-	for _, ip := range ips.IPv4CIDRs {
+	// the ipv6 cidrs are only whitelisted for the rules asking for them, see defaultIPFamily
+	for _, ip := range append(ips.IPv4CIDRs, ips.IPv6CIDRs...) {
 		parsedIPPrefix, err := netaddr.ParseIPPrefix(ip)
 		if err != nil {
-			return nil, err
+			return nil, badResponse("unable to parse ip %s: %v", ip, err)
 		}
 		cloudFlareIps = append(cloudFlareIps, parsedIPPrefix)
 	}
 
+	cached.store(resp, uri, "", cloudFlareIps, cidrTags{})
 	return cloudFlareIps, nil
 }
 
-func getGitHubCidrs(provider beta1.GithubProvider) ([]netaddr.IPPrefix, error) {
+// getGitHubCidrs returns the CIDRs of the services of the GitHub meta api, tagged with their service. It is not
+// requested while its rate limit is exhausted, and the token is sent if not empty.
+func getGitHubCidrs(ctx context.Context, httpClient *http.Client, cached *cachedResponse, limit *rateLimit, token string, provider beta1.GithubProvider) (_ []netaddr.IPPrefix, _ cidrTags, err error) {
 	var githubIPs []netaddr.IPPrefix
+	var tags cidrTags
+
+	ctx, span := tracer.Start(ctx, "getGitHubCidrs", trace.WithAttributes(attribute.String("url", provider.JsonApi)))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkGitHubFlavor(provider); err != nil {
+		return nil, cidrTags{}, err
+	}
+	if err := limit.wait(time.Now()); err != nil {
+		return nil, cidrTags{}, err
+	}
 
-	req, err := http.NewRequest(http.MethodGet, provider.JsonApi, nil) //nolint:gosec
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.JsonApi, nil) //nolint:gosec
 	if err != nil {
-		return nil, fmt.Errorf("client: could not create request: %s\n", err)
+		return nil, cidrTags{}, fmt.Errorf("client: could not create request: %s\n", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("X-GitHub-Api-Version", provider.APIVersion)
-	resp, err := http.DefaultClient.Do(req)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	// the cidrs are only parsed for the services, so the cached ones can only be used for the same services
+	key := strings.Join(append([]string{provider.JsonApi, string(provider.Flavor), provider.APIVersion}, provider.Services...), " ")
+	cached.apply(req, key)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make http call to github: %v", err)
+		return nil, cidrTags{}, unavailable("failed to make http call to github: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := limit.observe(resp, time.Now()); err != nil {
+		return nil, cidrTags{}, err
+	}
+	if cidrs, tags, ok := cached.notModified(resp, key); ok {
+		return cidrs, tags, nil
+	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readProviderResponse(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, cidrTags{}, err
 	}
 
-	cresp, err := utils.ConvertFromJSON(string(body))
+	meta, err := parseGitHubMeta(body, provider.Flavor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response body from GitHub Meta API: %v", err)
+		return nil, cidrTags{}, err
 	}
 	// check if the services given in the custom resource are present in the response
-	ok, notFound := utils.ArrayInArray(provider.Services, cresp.Keys())
-	if !ok {
-		return nil, fmt.Errorf("failed to %s in GitHub Meta API Response %s", notFound, cresp.Keys())
+	services, err := meta.selectServices(provider.Services)
+	if err != nil {
+		return nil, cidrTags{}, err
 	}
-	for _, services := range provider.Services {
-		var serviceIPs []string
-		cresp.Get(services).ToVal(&serviceIPs)
-		for _, ip := range serviceIPs {
+	for _, service := range services {
+		for _, ip := range meta.services[service] {
 			parsedIPPrefix, err := netaddr.ParseIPPrefix(ip)
 			if err != nil {
-				return nil, fmt.Errorf("unable to parse ip %s: %v", ip, err)
+				return nil, cidrTags{}, badResponse("unable to parse ip %s: %v", ip, err)
 			}
 			githubIPs = append(githubIPs, parsedIPPrefix)
+			tags.addService(service, parsedIPPrefix)
 		}
 	}
 
-	return githubIPs, nil
+	cached.store(resp, key, "", githubIPs, tags)
+	return githubIPs, tags, nil
 }
 
-func (r *IPWhitelistConfigReconciler) getAkamaiCidrs(ctx context.Context, provider beta1.AkamaiProvider) ([]netaddr.IPPrefix, error) {
-	akaClient, err := r.getsiteShieldClient(ctx, provider)
-	if err != nil {
-		return nil, err
+func (r *IPWhitelistConfigReconciler) getAkamaiMaps(ctx context.Context, httpClient *http.Client, provider beta1.AkamaiProvider) (_ []siteshield.Map, err error) {
+	ids := siteShieldMapIDs(provider)
+	ctx, span := tracer.Start(ctx, "getAkamaiMaps", trace.WithAttributes(attribute.IntSlice("mapIds", ids), attribute.Bool("allMaps", provider.AllMaps)))
+	defer func() { endSpan(span, err) }()
+
+	if !provider.AllMaps && len(ids) == 0 {
+		return nil, fmt.Errorf("mapId, mapIds or allMaps is required")
 	}
-	siteMap, err := akaClient.GetMap(provider.MapId.IntValue())
+	akaClient, err := r.getsiteShieldClient(ctx, httpClient, provider)
 	if err != nil {
 		return nil, err
 	}
-	if siteMap.ProposedCIDRs == nil || len(siteMap.ProposedCIDRs) == 0 {
-		return siteMap.CurrentCIDRs, nil
+	if provider.AllMaps {
+		return akaClient.getMaps(ctx)
 	}
-	return siteMap.ProposedCIDRs, nil
-}
-
-func (r *IPWhitelistConfigReconciler) getsiteShieldClient(ctx context.Context, provider beta1.AkamaiProvider) (*siteshield.Client, error) {
-	var host string
-	var clientSecret string
-	var clientToken string
-	var accessToken string
-	var err error
-
-	if provider.Host != nil {
-		if host, err = r.readSecretKey(ctx, provider.Host); err != nil {
+	maps := make([]siteshield.Map, 0, len(ids))
+	for _, id := range ids {
+		siteMap, err := akaClient.getMap(ctx, id)
+		if err != nil {
 			return nil, err
 		}
-	} else {
-		return nil, fmt.Errorf("host is required")
+		maps = append(maps, siteMap)
 	}
-	if provider.ClientToken != nil {
-		if clientToken, err = r.readSecretKey(ctx, provider.ClientToken); err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, fmt.Errorf("client token is required")
+	return maps, nil
+}
+
+// akamaiCidrs returns the cidrs of the site shield map to whitelist. Once the map is acknowledged and Akamai has moved
+// it, the proposed cidrs become the current ones and there are no proposed cidrs left.
+func akamaiCidrs(siteMap siteshield.Map, transition beta1.SiteShieldTransition) []netaddr.IPPrefix {
+	if siteMap.ProposedCIDRs == nil || len(siteMap.ProposedCIDRs) == 0 {
+		return siteMap.CurrentCIDRs
 	}
-	if provider.ClientSecret != nil {
-		if clientSecret, err = r.readSecretKey(ctx, provider.ClientSecret); err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, fmt.Errorf("client secret is required")
+	if transition != beta1.SiteShieldTransitionUnion {
+		return siteMap.ProposedCIDRs
 	}
-	if provider.AccessToken != nil {
-		if accessToken, err = r.readSecretKey(ctx, provider.AccessToken); err != nil {
-			return nil, err
+	cidrs := slices.Clone(siteMap.CurrentCIDRs)
+	for _, cidr := range siteMap.ProposedCIDRs {
+		if !slices.Contains(cidrs, cidr) {
+			cidrs = append(cidrs, cidr)
 		}
-	} else {
-		return nil, fmt.Errorf("access token is required")
 	}
-	cred := akamai.Credentials{
-		ClientSecret: clientSecret,
-		AccessToken:  accessToken,
-		ClientToken:  clientToken,
-		Host:         host,
-	}
-
-	shieldClient := siteshield.Client{Credentials: cred}
-	return &shieldClient, nil
+	return cidrs
 }
@@ -139,26 +139,34 @@ var (
 		},
 	}
 
-	adminGroup = beta1.IPGroup{
-		Name:    "admin",
-		Expires: metav1.Time{Time: time.Now().Add(2 * time.Hour)},
-		CIDRS:   []string{"192.169.0.1/32", "10.0.3.4/18"},
+	adminGroup = beta1.InlineIPGroup{
+		Name: "admin",
+		IPGroupSpec: beta1.IPGroupSpec{
+			Expires: &metav1.Time{Time: time.Now().Add(2 * time.Hour)},
+			CIDRS:   []beta1.CIDR{{CIDR: "192.169.0.1/32"}, {CIDR: "10.0.3.4/18"}},
+		},
 	}
-	publicGroup = beta1.IPGroup{
-		Name:    "public",
-		Expires: metav1.Time{Time: time.Now().Add(2 * time.Hour)},
-		CIDRS:   []string{"0.0.0.0/0", "::/0"},
+	publicGroup = beta1.InlineIPGroup{
+		Name: "public",
+		IPGroupSpec: beta1.IPGroupSpec{
+			Expires: &metav1.Time{Time: time.Now().Add(2 * time.Hour)},
+			CIDRS:   []beta1.CIDR{{CIDR: "0.0.0.0/0"}, {CIDR: "::/0"}},
+		},
 	}
-	devopsVPNGroup = beta1.IPGroup{
-		Name:    "devopsVPN",
-		Expires: metav1.Time{Time: time.Now().Add(2 * time.Hour)},
-		CIDRS:   []string{"176.34.201.164/32"},
+	devopsVPNGroup = beta1.InlineIPGroup{
+		Name: "devopsVPN",
+		IPGroupSpec: beta1.IPGroupSpec{
+			Expires: &metav1.Time{Time: time.Now().Add(2 * time.Hour)},
+			CIDRS:   []beta1.CIDR{{CIDR: "176.34.201.164/32"}},
+		},
 	}
-	siteAGroup = beta1.IPGroup{
-		Name:    "siteA-vpn",
-		Expires: metav1.Time{Time: time.Now().Add(2 * time.Hour)},
-		CIDRS: []string{
-			"156.75.1.1/24",
+	siteAGroup = beta1.InlineIPGroup{
+		Name: "siteA-vpn",
+		IPGroupSpec: beta1.IPGroupSpec{
+			Expires: &metav1.Time{Time: time.Now().Add(2 * time.Hour)},
+			CIDRS: []beta1.CIDR{
+				{CIDR: "156.75.1.1/24"},
+			},
 		},
 	}
 )
@@ -179,7 +187,7 @@ var _ = Describe("IPWhitelistConfig controller Cloudflare", Ordered, func() {
 				cfRule,
 				devopsOnlyRule,
 			},
-			IPGroups: []beta1.IPGroup{
+			IPGroups: []beta1.InlineIPGroup{
 				adminGroup,
 				publicGroup,
 				devopsVPNGroup,
@@ -445,7 +453,7 @@ var _ = Describe("IPWhitelistConfig controller Cloudflare", Ordered, func() {
 // 			Rules: []beta1.Rule{
 // 				adminRule,
 // 			},
-// 			IPGroups: []beta1.IPGroup{
+// 			IPGroups: []beta1.InlineIPGroup{
 // 				adminGroup,
 // 			},
 // 			Providers: []beta1.Providers{
@@ -642,7 +650,9 @@ var _ = Describe("CloudFlare Provider Test", func() {
 			prov := beta1.CloudflareProvider{
 				JsonApi: "https://api.cloudflare.com/client/v4/ips",
 			}
-			got, err := getCloudFlareCidrs(prov)
+			got, err := getCloudFlareCidrs(ctx, providerHTTPClient, nil, prov)
+			Expect(err).ToNot(HaveOccurred())
+			got, err = filterProviderCIDRs(beta1.Providers{Type: beta1.Cloudflare}, nil, got, cidrTags{})
 			Expect(err).ToNot(HaveOccurred())
 			Expect(got).To(HaveLen(len(ipv4)))
 			for _, cidr := range ipv4 {
@@ -674,7 +684,7 @@ var _ = Describe("GitHub Provider Test", func() {
 				JsonApi:  "https://api.github.com/meta",
 				Services: []string{"hooks"},
 			}
-			got, err := getGitHubCidrs(prov)
+			got, _, err := getGitHubCidrs(ctx, providerHTTPClient, nil, nil, "", prov)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(got).To(HaveLen(len(ipv4)))
 			for _, cidr := range ipv4 {
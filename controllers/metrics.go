@@ -0,0 +1,82 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "ingress_whitelister"
+
+var (
+	// ipGroupExpiryTimestamp exposes the expiry of every IPGroup so alerts can be built on top of it
+	ipGroupExpiryTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "ipgroup_expiry_timestamp_seconds",
+		Help:      "Unix timestamp at which the IPGroup expires.",
+	}, []string{"ipgroup"})
+
+	// ipGroupExpiring is 1 for every IPGroup which expires within the warning window
+	ipGroupExpiring = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "ipgroup_expiring",
+		Help:      "1 if the IPGroup expires within the configured warning window, 0 otherwise.",
+	}, []string{"ipgroup"})
+
+	// providerHeldBack is 1 for every provider whose last fetch is held back by its sanity checks
+	providerHeldBack = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_held_back",
+		Help:      "1 if the last fetch of the provider is held back until it is acknowledged, 0 otherwise.",
+	}, []string{"provider"})
+
+	// providerFetchErrors counts the failed fetches of every provider by the kind of error, to tell an upstream outage
+	// from a provider returning bad data
+	providerFetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_fetch_errors_total",
+		Help:      "Number of failed fetches of the provider by kind, Unavailable, BadResponse or Unknown.",
+	}, []string{"provider", "kind"})
+
+	// providerResponseCache counts the responses of the http providers by whether they were not modified since the last
+	// fetch, hit, or had to be parsed again, miss
+	providerResponseCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_response_cache_total",
+		Help:      "Number of responses of the provider by result, hit when not modified since the last fetch, otherwise miss.",
+	}, []string{"provider", "result"})
+
+	// providerRateLimitRemaining is the number of requests left before the rate limit of the provider is exhausted
+	providerRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_rate_limit_remaining",
+		Help:      "Number of requests left in the rate limit window of the provider, from its X-RateLimit-Remaining.",
+	}, []string{"provider"})
+)
+
+func init() {
+	// Register the custom metrics with the global prometheus registry of controller-runtime
+	metrics.Registry.MustRegister(
+		ipGroupExpiryTimestamp,
+		ipGroupExpiring,
+		providerHeldBack,
+		providerFetchErrors,
+		providerResponseCache,
+		providerRateLimitRemaining,
+	)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const reasonCIDRRefused = "CIDRRefused"
+
+// namespaceCIDRs returns the whitelisted cidrs of the NamespaceIPGroups selected in the namespace of the ingress,
+// along with the next time this will change on its own. CIDRs violating the policy of the selector, or the policy of
+// the config if given, are left out, with a Warning event on the NamespaceIPGroup they came from. The forbiddenCIDRs
+// are checked against all the cidrs of the namespace together, so that they can not be whitelisted split up over
+// several cidrs or groups either.
+func (r *IPWhitelistConfigReconciler) namespaceCIDRs(ctx context.Context, ing *knet.Ingress, selector *beta1.NamespaceIPGroupSelector, configPolicy *beta1.CIDRPolicy, now time.Time) ([]string, time.Time, error) {
+	groupSelector := labels.Everything()
+	if selector.Selector != nil {
+		var err error
+		if groupSelector, err = metav1.LabelSelectorAsSelector(selector.Selector); err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid namespaceIPGroups selector: %w", err)
+		}
+	}
+	policies := []beta1.CIDRPolicy{selector.Policy}
+	if configPolicy != nil {
+		policies = append(policies, *configPolicy)
+	}
+
+	var list beta1.NamespaceIPGroupList
+	if err := r.List(ctx, &list, client.InNamespace(ing.Namespace)); err != nil {
+		return nil, time.Time{}, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	// includes are resolved against all the groups of the namespace, not only the selected ones
+	var inline []beta1.InlineIPGroup
+	for _, object := range list.Items {
+		inline = append(inline, object.Inline())
+	}
+	groups := indexIPGroups(inline)
+
+	// the refused cidrs are warned about once per ingress, group and cidr, not on every reconcile
+	eventPrefix := fmt.Sprintf("%s/%s/%s ", reasonCIDRRefused, ing.Namespace, ing.Name)
+	refused := make(map[string]bool)
+	refuse := func(object *beta1.NamespaceIPGroup, cidr string, err error) {
+		key := eventPrefix + object.Name + " " + cidr
+		refused[key] = true
+		if r.events.changed(key, err.Error()) {
+			r.Recorder.Eventf(object, corev1.EventTypeWarning, reasonCIDRRefused, "not whitelisting %s for ingress %s: %s", cidr, ing.Name, err)
+		}
+	}
+	defer func() { r.events.forgetOthers(eventPrefix, refused) }()
+
+	var candidates []namespaceCIDR
+	var next time.Time
+	for i := range list.Items {
+		object := &list.Items[i]
+		if !groupSelector.Matches(labels.Set(object.GetLabels())) {
+			continue
+		}
+		groupCIDRs, groupNext, err := resolveIPGroup(object.Inline(), groups, now)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("namespaceIPGroup %s/%s: %w", object.Namespace, object.Name, err)
+		}
+		next = earliest(next, groupNext)
+		for _, cidr := range groupCIDRs {
+			if err := checkPrefixLengths(policies, cidr); err != nil {
+				refuse(object, cidr, err)
+				continue
+			}
+			candidates = append(candidates, namespaceCIDR{object: object, cidr: cidr})
+		}
+	}
+
+	var all []string
+	for _, candidate := range candidates {
+		all = append(all, candidate.cidr)
+	}
+	forbidden := make(map[string]string)
+	for _, policy := range policies {
+		overlaps, err := forbiddenOverlaps(policy, all)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, forbiddenCIDR := range policy.ForbiddenCIDRs {
+			for _, cidr := range overlaps[forbiddenCIDR] {
+				if _, ok := forbidden[cidr]; !ok {
+					forbidden[cidr] = forbiddenCIDR
+				}
+			}
+		}
+	}
+	var cidrs []string
+	for _, candidate := range candidates {
+		if forbiddenCIDR, ok := forbidden[candidate.cidr]; ok {
+			refuse(candidate.object, candidate.cidr, fmt.Errorf("cidr %s overlaps the forbidden cidr %s", candidate.cidr, forbiddenCIDR))
+			continue
+		}
+		cidrs = append(cidrs, candidate.cidr)
+	}
+
+	return cidrs, next, nil
+}
+
+// namespaceCIDR is a cidr along with the NamespaceIPGroup it came from
+type namespaceCIDR struct {
+	object *beta1.NamespaceIPGroup
+	cidr   string
+}
+
+// checkPrefixLengths returns the first violation of the minimum prefix lengths of the policies by the cidr
+func checkPrefixLengths(policies []beta1.CIDRPolicy, cidr string) error {
+	for _, policy := range policies {
+		if err := checkPrefixLength(policy, cidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingressesForNamespaceIPGroup maps a NamespaceIPGroup to the ingresses in its namespace matching the rules that opt in
+// to NamespaceIPGroups, so that changes to it are applied right away
+func (r *IPWhitelistConfigReconciler) ingressesForNamespaceIPGroup(ctx context.Context, obj client.Object) []reconcile.Request {
+	logo := r.Log.WithValues("namespaceIPGroup", client.ObjectKeyFromObject(obj))
+
+	config, err := r.getIPWhitelistConfig(ctx)
+	if err != nil {
+		logo.Error(err, "failed to get the IPWhitelistConfig")
+		return nil
+	}
+	var selectors []labels.Selector
+	for _, rule := range config.Spec.Rules {
+		if rule.NamespaceIPGroups == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(rule.Selector)
+		if err != nil {
+			logo.Error(err, "failed to convert the labelSelector to selector", "rule", rule.Name)
+			continue
+		}
+		selectors = append(selectors, selector)
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+
+	var ingresses knet.IngressList
+	if err := r.List(ctx, &ingresses, client.InNamespace(obj.GetNamespace())); err != nil {
+		logo.Error(err, "failed to list the ingresses")
+		return nil
+	}
+	return ingressRequests(ingresses.Items, selectors)
+}
+
+// ingressRequests returns the requests for the ingresses matching any of the selectors
+func ingressRequests(ingresses []knet.Ingress, selectors []labels.Selector) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, ing := range ingresses {
+		for _, selector := range selectors {
+			if selector.Matches(labels.Set(ing.GetLabels())) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&ing)})
+				break
+			}
+		}
+	}
+	return requests
+}
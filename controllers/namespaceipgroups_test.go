@@ -0,0 +1,160 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("NamespaceIPGroups", func() {
+	ctx := context.Background()
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	minPrefix := int32(24)
+
+	group := func(namespace, name string, groupLabels map[string]string, cidrs ...string) *beta1.NamespaceIPGroup {
+		g := &beta1.NamespaceIPGroup{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: groupLabels}}
+		for _, cidr := range cidrs {
+			g.Spec.CIDRS = append(g.Spec.CIDRS, beta1.CIDR{CIDR: cidr})
+		}
+		return g
+	}
+	ingress := func(namespace, name string, ingressLabels map[string]string) *knet.Ingress {
+		return &knet.Ingress{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: ingressLabels}}
+	}
+
+	var r *IPWhitelistConfigReconciler
+	var recorder *record.FakeRecorder
+	BeforeEach(func() {
+		expires := group("shop", "contractor", nil, "198.51.100.0/24")
+		expires.Spec.CIDRS[0].Expires = &metav1.Time{Time: now.Add(time.Hour)}
+
+		testScheme := runtime.NewScheme()
+		Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+		Expect(knet.AddToScheme(testScheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		r = &IPWhitelistConfigReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				&beta1.IPWhitelistConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "ruleset"},
+					Spec: beta1.IPWhitelistConfigSpec{Rules: []beta1.Rule{
+						{Name: "admin", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"ipwhitelist-type": "admin"}}},
+						{
+							Name:              "tenant",
+							Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"ipwhitelist-type": "tenant"}},
+							NamespaceIPGroups: &beta1.NamespaceIPGroupSelector{},
+						},
+					}},
+				},
+				group("shop", "office", map[string]string{"team": "shop"}, "203.0.113.0/24"),
+				group("shop", "wide", map[string]string{"team": "shop"}, "10.0.0.0/8"),
+				expires,
+				group("sandbox", "office", map[string]string{"team": "shop"}, "192.0.2.0/24"),
+				ingress("shop", "web", map[string]string{"ipwhitelist-type": "tenant"}),
+				ingress("shop", "admin", map[string]string{"ipwhitelist-type": "admin"}),
+				ingress("sandbox", "web", map[string]string{"ipwhitelist-type": "tenant"}),
+			).Build(),
+			IPWhitelistConfig: "ruleset",
+			Log:               logr.Discard(),
+			Recorder:          recorder,
+		}
+	})
+
+	Context("When getting the cidrs for an ingress", func() {
+		It("should only whitelist the selected groups of the namespace of the ingress", func() {
+			selector := &beta1.NamespaceIPGroupSelector{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "shop"}}}
+			cidrs, _, err := r.namespaceCIDRs(ctx, ingress("shop", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"203.0.113.0/24", "10.0.0.0/8"}))
+
+			By("selecting all the groups of the namespace without a selector")
+			cidrs, next, err := r.namespaceCIDRs(ctx, ingress("shop", "web", nil), &beta1.NamespaceIPGroupSelector{}, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"198.51.100.0/24", "203.0.113.0/24", "10.0.0.0/8"}))
+			Expect(next).To(BeTemporally("==", now.Add(time.Hour)))
+		})
+		It("should leave out the cidrs violating the policy of the selector or of the config", func() {
+			selector := &beta1.NamespaceIPGroupSelector{Policy: beta1.CIDRPolicy{MinPrefixLengthIPv4: &minPrefix}}
+			cidrs, _, err := r.namespaceCIDRs(ctx, ingress("shop", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"198.51.100.0/24", "203.0.113.0/24"}))
+			Expect(recorder.Events).To(Receive(ContainSubstring("not whitelisting 10.0.0.0/8 for ingress web")))
+
+			cidrs, _, err = r.namespaceCIDRs(ctx, ingress("shop", "web", nil), &beta1.NamespaceIPGroupSelector{},
+				&beta1.CIDRPolicy{ForbiddenCIDRs: []string{"203.0.113.0/24"}}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"198.51.100.0/24", "10.0.0.0/8"}))
+			Expect(recorder.Events).To(Receive(ContainSubstring("not whitelisting 203.0.113.0/24 for ingress web")))
+		})
+		It("should refuse a forbidden cidr split up over several groups", func() {
+			Expect(r.Create(ctx, group("split", "first-half", nil, "10.0.0.0/9"))).To(Succeed())
+			Expect(r.Create(ctx, group("split", "second-half", nil, "10.128.0.0/9", "203.0.113.0/24"))).To(Succeed())
+			selector := &beta1.NamespaceIPGroupSelector{Policy: beta1.CIDRPolicy{ForbiddenCIDRs: []string{"10.0.0.0/8"}}}
+			cidrs, _, err := r.namespaceCIDRs(ctx, ingress("split", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"203.0.113.0/24"}))
+			Expect(recorder.Events).To(Receive(ContainSubstring("not whitelisting 10.0.0.0/9 for ingress web: cidr 10.0.0.0/9 overlaps the forbidden cidr 10.0.0.0/8")))
+			Expect(recorder.Events).To(Receive(ContainSubstring("not whitelisting 10.128.0.0/9 for ingress web")))
+		})
+		It("should warn about a refused cidr once until it is removed", func() {
+			selector := &beta1.NamespaceIPGroupSelector{Policy: beta1.CIDRPolicy{MinPrefixLengthIPv4: &minPrefix}}
+			_, _, err := r.namespaceCIDRs(ctx, ingress("shop", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("not whitelisting 10.0.0.0/8 for ingress web")))
+			_, _, err = r.namespaceCIDRs(ctx, ingress("shop", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).ToNot(Receive())
+
+			By("warning again once the cidr is added back after it was removed")
+			wide := &beta1.NamespaceIPGroup{}
+			Expect(r.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "wide"}, wide)).To(Succeed())
+			Expect(r.Delete(ctx, wide)).To(Succeed())
+			_, _, err = r.namespaceCIDRs(ctx, ingress("shop", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Create(ctx, group("shop", "wide", nil, "10.0.0.0/8"))).To(Succeed())
+			_, _, err = r.namespaceCIDRs(ctx, ingress("shop", "web", nil), selector, nil, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("not whitelisting 10.0.0.0/8 for ingress web")))
+		})
+	})
+
+	Context("When a NamespaceIPGroup changes", func() {
+		It("should requeue the ingresses of its namespace matching the rules opting in to NamespaceIPGroups", func() {
+			requests := r.ingressesForNamespaceIPGroup(ctx, group("shop", "office", nil))
+			Expect(requests).To(Equal([]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "shop", Name: "web"}}}))
+		})
+		It("should not requeue anything when no rule opts in", func() {
+			config := &beta1.IPWhitelistConfig{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "ruleset"}, config)).To(Succeed())
+			config.Spec.Rules = config.Spec.Rules[:1]
+			Expect(r.Update(ctx, config)).To(Succeed())
+			Expect(r.ingressesForNamespaceIPGroup(ctx, group("shop", "office", nil))).To(BeEmpty())
+		})
+	})
+})
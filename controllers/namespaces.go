@@ -0,0 +1,92 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const reasonRuleRefused = "RuleRefused"
+
+// namespaceAllowed returns true if the ingresses in the namespace may use a rule with the given allowedNamespaces.
+// The namespace is only fetched when it is not allowed by name already.
+func (r *IPWhitelistConfigReconciler) namespaceAllowed(ctx context.Context, allowed *beta1.AllowedNamespaces, namespace string) (bool, error) {
+	if allowed == nil {
+		return true, nil
+	}
+	if slices.Contains(allowed.Names, namespace) {
+		return true, nil
+	}
+	if allowed.Selector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(allowed.Selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid allowedNamespaces selector: %w", err)
+	}
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(ns.GetLabels())), nil
+}
+
+// ingressesForNamespace maps a Namespace to the ingresses in it matching the rules allowing namespaces by label, so
+// that a change to its labels is applied right away rather than on the next requeue
+func (r *IPWhitelistConfigReconciler) ingressesForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	logo := r.Log.WithValues("namespace", obj.GetName())
+
+	config, err := r.getIPWhitelistConfig(ctx)
+	if err != nil {
+		logo.Error(err, "failed to get the IPWhitelistConfig")
+		return nil
+	}
+	var selectors []labels.Selector
+	for _, rule := range config.Spec.Rules {
+		if rule.AllowedNamespaces == nil || rule.AllowedNamespaces.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(rule.Selector)
+		if err != nil {
+			logo.Error(err, "failed to convert the labelSelector to selector", "rule", rule.Name)
+			continue
+		}
+		selectors = append(selectors, selector)
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+
+	var ingresses knet.IngressList
+	if err := r.List(ctx, &ingresses, client.InNamespace(obj.GetName())); err != nil {
+		logo.Error(err, "failed to list the ingresses")
+		return nil
+	}
+	return ingressRequests(ingresses.Items, selectors)
+}
@@ -0,0 +1,125 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("Allowed namespaces", func() {
+	ctx := context.Background()
+	r := IPWhitelistConfigReconciler{
+		Client: fake.NewClientBuilder().WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"tier": "public"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}},
+		).Build(),
+	}
+	allowed := &beta1.AllowedNamespaces{
+		Names:    []string{"platform"},
+		Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "public"}},
+	}
+
+	It("should allow every namespace when the rule does not restrict them", func() {
+		Expect(r.namespaceAllowed(ctx, nil, "sandbox")).To(BeTrue())
+	})
+	It("should allow the namespaces named or matching the selector", func() {
+		Expect(r.namespaceAllowed(ctx, allowed, "platform")).To(BeTrue())
+		Expect(r.namespaceAllowed(ctx, allowed, "shop")).To(BeTrue())
+	})
+	It("should refuse any other namespace", func() {
+		Expect(r.namespaceAllowed(ctx, allowed, "sandbox")).To(BeFalse())
+		Expect(r.namespaceAllowed(ctx, &beta1.AllowedNamespaces{Names: []string{"platform"}}, "shop")).To(BeFalse())
+	})
+
+	Context("When the namespace of a whitelisted ingress is no longer allowed", func() {
+		config := &beta1.IPWhitelistConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "ruleset"},
+			Spec: beta1.IPWhitelistConfigSpec{
+				WhitelistAnnotation: "ingress.kubernetes.io/whitelist-source-range",
+				Rules: []beta1.Rule{{
+					Name:              "admin",
+					Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"ipwhitelist-type": "admin"}},
+					AllowedNamespaces: allowed,
+					IPGroupSelector:   []string{"admin"},
+				}},
+				IPGroups: []beta1.InlineIPGroup{{Name: "admin", IPGroupSpec: beta1.IPGroupSpec{CIDRS: []beta1.CIDR{{CIDR: "192.0.2.0/24"}}}}},
+			},
+		}
+		var recorder *record.FakeRecorder
+		var reconciler *IPWhitelistConfigReconciler
+		BeforeEach(func() {
+			testScheme := runtime.NewScheme()
+			Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+			Expect(knet.AddToScheme(testScheme)).To(Succeed())
+			Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &IPWhitelistConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&beta1.IPWhitelistConfig{}).WithObjects(
+					config.DeepCopy(),
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}},
+					&knet.Ingress{ObjectMeta: metav1.ObjectMeta{
+						Name:        "admin",
+						Namespace:   "sandbox",
+						Labels:      map[string]string{"ipwhitelist-type": "admin"},
+						Annotations: map[string]string{config.Spec.WhitelistAnnotation: "192.0.2.0/24"},
+					}},
+				).Build(),
+				IPWhitelistConfig: "ruleset",
+				RequeueInterval:   time.Hour,
+				Log:               logr.Discard(),
+				Recorder:          recorder,
+			}
+		})
+
+		It("should deny all instead of removing the whitelist, and warn once", func() {
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "sandbox", Name: "admin"}}
+			_, err := reconciler.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			ing := &knet.Ingress{}
+			Expect(reconciler.Get(ctx, req.NamespacedName, ing)).To(Succeed())
+			Expect(ing.Annotations).To(HaveKeyWithValue(config.Spec.WhitelistAnnotation, denyAllWhitelist))
+			Expect(recorder.Events).To(Receive(ContainSubstring("rule admin is not allowed in namespace sandbox")))
+
+			_, err = reconciler.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).ToNot(Receive())
+		})
+		It("should requeue the ingresses of a namespace whose labels change", func() {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}}
+			Expect(reconciler.ingressesForNamespace(ctx, ns)).To(Equal([]reconcile.Request{
+				{NamespacedName: client.ObjectKey{Namespace: "sandbox", Name: "admin"}},
+			}))
+			Expect(reconciler.ingressesForNamespace(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}})).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,260 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+
+	"inet.af/netaddr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const (
+	reasonPolicyViolated = "PolicyViolated"
+	reasonPolicyMet      = "PolicyMet"
+)
+
+// parseCIDR parses a CIDR, a single IP without a prefix length is treated as a /32 or /128
+func parseCIDR(cidr string) (netaddr.IPPrefix, error) {
+	if !strings.Contains(cidr, "/") {
+		ip, err := netaddr.ParseIP(cidr)
+		if err != nil {
+			return netaddr.IPPrefix{}, err
+		}
+		return netaddr.IPPrefixFrom(ip, ip.BitLen()), nil
+	}
+	return netaddr.ParseIPPrefix(cidr)
+}
+
+// checkCIDRPolicy returns an error describing why the cidr violates the policy, or nil if it does not
+func checkCIDRPolicy(policy beta1.CIDRPolicy, cidr string) error {
+	return checkPolicyCIDRs(policy, []string{cidr})
+}
+
+// checkPolicyCIDRs returns the violations of the policy by the cidrs, or nil if there are none. The forbiddenCIDRs are
+// checked against the set of all the cidrs, so that a forbidden cidr can neither be whitelisted in part nor by
+// splitting it up into smaller cidrs.
+func checkPolicyCIDRs(policy beta1.CIDRPolicy, cidrs []string) error {
+	var violations []error
+	for _, cidr := range cidrs {
+		if err := checkPrefixLength(policy, cidr); err != nil {
+			violations = append(violations, err)
+		}
+	}
+	overlaps, err := forbiddenOverlaps(policy, cidrs)
+	if err != nil {
+		return err
+	}
+	for _, forbidden := range policy.ForbiddenCIDRs {
+		switch overlapping := overlaps[forbidden]; len(overlapping) {
+		case 0:
+		case 1:
+			violations = append(violations, fmt.Errorf("cidr %s overlaps the forbidden cidr %s", overlapping[0], forbidden))
+		default:
+			violations = append(violations, fmt.Errorf("cidrs %s overlap the forbidden cidr %s", strings.Join(overlapping, ", "), forbidden))
+		}
+	}
+	return errors.Join(violations...)
+}
+
+// checkPrefixLength returns an error if the cidr is invalid or larger than the minimum prefix length of the policy
+func checkPrefixLength(policy beta1.CIDRPolicy, cidr string) error {
+	prefix, err := parseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid cidr %s: %w", cidr, err)
+	}
+	minPrefixLength := policy.MinPrefixLengthIPv6
+	if prefix.IP().Is4() {
+		minPrefixLength = policy.MinPrefixLengthIPv4
+	}
+	if minPrefixLength != nil && int32(prefix.Bits()) < *minPrefixLength {
+		return fmt.Errorf("cidr %s is larger than the allowed prefix length /%d", cidr, *minPrefixLength)
+	}
+	return nil
+}
+
+// forbiddenOverlaps returns the cidrs overlapping each forbidden cidr of the policy that the set of all the cidrs
+// overlaps, invalid cidrs are left out
+func forbiddenOverlaps(policy beta1.CIDRPolicy, cidrs []string) (map[string][]string, error) {
+	set := cidrSet(cidrs)
+	overlaps := make(map[string][]string)
+	for _, forbidden := range policy.ForbiddenCIDRs {
+		forbiddenPrefix, err := parseCIDR(forbidden)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forbidden cidr %s in the policy: %w", forbidden, err)
+		}
+		forbiddenPrefix = forbiddenPrefix.Masked()
+		if !set.OverlapsPrefix(forbiddenPrefix) {
+			continue
+		}
+		for _, cidr := range cidrs {
+			if prefix, err := parseCIDR(cidr); err == nil && prefix.Masked().Overlaps(forbiddenPrefix) {
+				overlaps[forbidden] = append(overlaps[forbidden], cidr)
+			}
+		}
+	}
+	return overlaps, nil
+}
+
+// cidrSet returns the set of the addresses of the cidrs, invalid ones are left out
+func cidrSet(cidrs []string) *netaddr.IPSet {
+	var builder netaddr.IPSetBuilder
+	for _, cidr := range cidrs {
+		if prefix, err := parseCIDR(cidr); err == nil {
+			builder.AddPrefix(prefix.Masked())
+		}
+	}
+	set, err := builder.IPSet()
+	if err != nil {
+		return &netaddr.IPSet{}
+	}
+	return set
+}
+
+// countAddresses returns the number of distinct addresses in the cidrs, overlapping cidrs are only counted once and
+// invalid ones not at all
+func countAddresses(cidrs []string) *big.Int {
+	total := new(big.Int)
+	for _, prefix := range cidrSet(cidrs).Prefixes() {
+		total.Add(total, new(big.Int).Lsh(big.NewInt(1), uint(prefix.IP().BitLen()-prefix.Bits())))
+	}
+	return total
+}
+
+// checkRulePolicy returns the violations of the policy and of the maxAddresses of the rule by the given cidrs of its
+// ipGroups, or nil if there are none
+func checkRulePolicy(policy *beta1.CIDRPolicy, rule beta1.Rule, cidrs []string) error {
+	var violations []error
+	if policy != nil {
+		if err := checkPolicyCIDRs(*policy, cidrs); err != nil {
+			violations = append(violations, err)
+		}
+	}
+	if rule.MaxAddresses != nil {
+		if count := countAddresses(cidrs); count.Cmp(big.NewInt(*rule.MaxAddresses)) > 0 {
+			violations = append(violations, fmt.Errorf("the ipGroups whitelist %s addresses, more than the maxAddresses %d", count, *rule.MaxAddresses))
+		}
+	}
+	return errors.Join(violations...)
+}
+
+// allIPGroupCIDRs returns every cidr the group and the groups it includes may ever whitelist, no matter if they are
+// active right now, so that the policy can be checked once and not only when a group activates
+func allIPGroupCIDRs(group beta1.InlineIPGroup, groups map[string]beta1.InlineIPGroup, path []string) ([]string, error) {
+	if slices.Contains(path, group.Name) {
+		return nil, fmt.Errorf("ipGroup cycle detected: %s -> %s", strings.Join(path, " -> "), group.Name)
+	}
+	path = append(path, group.Name)
+
+	var cidrs []string
+	for _, cidr := range group.CIDRS {
+		cidrs = append(cidrs, cidr.CIDR)
+	}
+	for _, name := range group.IncludeGroups {
+		included, ok := groups[name]
+		if !ok {
+			return nil, fmt.Errorf("ipGroup %s includes the ipGroup %s which does not exist", group.Name, name)
+		}
+		includedCIDRs, err := allIPGroupCIDRs(included, groups, path)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, includedCIDRs...)
+	}
+	return cidrs, nil
+}
+
+// policyViolations returns the violations by rule name of the rules whose ipGroups violate the policy of the config or
+// the maxAddresses of the rule. The NamespaceIPGroups are not known up front, they are checked for every ingress.
+func policyViolations(config *beta1.IPWhitelistConfig, objects []beta1.IPGroup) map[string]error {
+	groups := indexIPGroups(mergeIPGroups(config.Spec.IPGroups, objects))
+	violations := make(map[string]error)
+	for _, rule := range config.Spec.Rules {
+		if config.Spec.Policy == nil && rule.MaxAddresses == nil {
+			continue
+		}
+		names, err := ruleIPGroups(rule, objects)
+		if err != nil {
+			violations[rule.Name] = err
+			continue
+		}
+		var cidrs []string
+		for _, name := range names {
+			group, ok := groups[name]
+			if !ok {
+				continue
+			}
+			groupCIDRs, err := allIPGroupCIDRs(group, groups, nil)
+			if err != nil {
+				violations[rule.Name] = err
+				break
+			}
+			cidrs = append(cidrs, groupCIDRs...)
+		}
+		if violations[rule.Name] != nil {
+			continue
+		}
+		if err := checkRulePolicy(config.Spec.Policy, rule, cidrs); err != nil {
+			violations[rule.Name] = err
+		}
+	}
+	return violations
+}
+
+// formatViolations returns the violations as a single line, sorted by rule name
+func formatViolations(violations map[string]error) string {
+	var messages []string
+	for rule, err := range violations {
+		messages = append(messages, fmt.Sprintf("rule %s: %s", rule, strings.ReplaceAll(err.Error(), "\n", ", ")))
+	}
+	sort.Strings(messages)
+	return strings.Join(messages, "; ")
+}
+
+// updatePolicyStatus sets the PolicyViolated condition on the config, an event is raised on the config whenever the
+// rules violating the policy change
+func (r *IPWhitelistConfigReconciler) updatePolicyStatus(ctx context.Context, config *beta1.IPWhitelistConfig, violations map[string]error) error {
+	condition := metav1.Condition{
+		Type:    beta1.ConditionPolicyViolated,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonPolicyMet,
+		Message: "all rules meet the policy",
+	}
+	if len(violations) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonPolicyViolated
+		condition.Message = "rules not applied: " + formatViolations(violations)
+	}
+
+	changed, err := r.setConfigCondition(ctx, config, condition)
+	if err != nil {
+		return err
+	}
+	if changed && condition.Status == metav1.ConditionTrue {
+		r.Recorder.Event(config, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+	return nil
+}
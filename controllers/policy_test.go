@@ -0,0 +1,146 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("CIDR policy", func() {
+	minPrefix := func(bits int32) *int32 {
+		return &bits
+	}
+	policy := beta1.CIDRPolicy{
+		ForbiddenCIDRs:      []string{"10.0.0.0/8", "fe80::/10"},
+		MinPrefixLengthIPv4: minPrefix(24),
+		MinPrefixLengthIPv6: minPrefix(48),
+	}
+
+	Context("When the cidr is within the bounds", func() {
+		It("should allow it", func() {
+			Expect(checkCIDRPolicy(policy, "203.0.113.0/24")).To(Succeed())
+			Expect(checkCIDRPolicy(policy, "203.0.113.7")).To(Succeed())
+			Expect(checkCIDRPolicy(policy, "2001:db8::/48")).To(Succeed())
+		})
+		It("should allow anything without a policy", func() {
+			Expect(checkCIDRPolicy(beta1.CIDRPolicy{}, "0.0.0.0/0")).To(Succeed())
+		})
+	})
+
+	Context("When the cidr violates the policy", func() {
+		It("should refuse forbidden cidrs and the ones containing them", func() {
+			Expect(checkCIDRPolicy(policy, "10.0.0.0/8")).To(MatchError(ContainSubstring("forbidden cidr 10.0.0.0/8")))
+			Expect(checkCIDRPolicy(policy, "fe80::/10")).To(MatchError(ContainSubstring("forbidden cidr fe80::/10")))
+			Expect(checkCIDRPolicy(beta1.CIDRPolicy{ForbiddenCIDRs: []string{"10.0.0.0/8"}}, "8.0.0.0/5")).To(HaveOccurred())
+		})
+		It("should refuse cidrs inside of a forbidden cidr", func() {
+			Expect(checkCIDRPolicy(beta1.CIDRPolicy{ForbiddenCIDRs: []string{"10.0.0.0/8"}}, "10.1.0.0/16")).
+				To(MatchError("cidr 10.1.0.0/16 overlaps the forbidden cidr 10.0.0.0/8"))
+			Expect(checkCIDRPolicy(policy, "10.1.2.3")).To(MatchError(ContainSubstring("forbidden cidr 10.0.0.0/8")))
+		})
+		It("should refuse a forbidden cidr split up into smaller cidrs", func() {
+			rule := beta1.Rule{Name: "admin"}
+			err := checkRulePolicy(&beta1.CIDRPolicy{ForbiddenCIDRs: []string{"0.0.0.0/0"}}, rule, []string{"0.0.0.0/1", "128.0.0.0/1"})
+			Expect(err).To(MatchError("cidrs 0.0.0.0/1, 128.0.0.0/1 overlap the forbidden cidr 0.0.0.0/0"))
+		})
+		It("should refuse cidrs larger than the allowed prefix length", func() {
+			Expect(checkCIDRPolicy(policy, "203.0.112.0/23")).To(MatchError(ContainSubstring("/24")))
+			Expect(checkCIDRPolicy(policy, "2001:db8::/32")).To(MatchError(ContainSubstring("/48")))
+		})
+		It("should refuse invalid cidrs", func() {
+			Expect(checkCIDRPolicy(policy, "office")).To(HaveOccurred())
+		})
+	})
+
+	Context("When counting the addresses of a rule", func() {
+		It("should count overlapping cidrs once", func() {
+			Expect(countAddresses([]string{"10.0.0.0/24", "10.0.0.128/25", "10.0.1.1"}).Int64()).To(Equal(int64(257)))
+			Expect(countAddresses([]string{"2001:db8::/64"}).String()).To(Equal("18446744073709551616"))
+		})
+		It("should refuse rules whitelisting more than maxAddresses", func() {
+			maxAddresses := int64(256)
+			rule := beta1.Rule{Name: "admin", MaxAddresses: &maxAddresses}
+			Expect(checkRulePolicy(nil, rule, []string{"10.0.0.0/24"})).To(Succeed())
+			Expect(checkRulePolicy(nil, rule, []string{"10.0.0.0/24", "10.0.1.1"})).To(MatchError(ContainSubstring("257 addresses")))
+		})
+	})
+
+	Context("When checking the rules of a config", func() {
+		group := func(name string, cidrs ...string) beta1.InlineIPGroup {
+			g := beta1.InlineIPGroup{Name: name}
+			for _, cidr := range cidrs {
+				g.CIDRS = append(g.CIDRS, beta1.CIDR{CIDR: cidr})
+			}
+			return g
+		}
+		config := func() *beta1.IPWhitelistConfig {
+			return &beta1.IPWhitelistConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "ruleset"},
+				Spec: beta1.IPWhitelistConfigSpec{
+					Policy: &beta1.CIDRPolicy{ForbiddenCIDRs: []string{"10.0.0.0/8"}},
+					Rules: []beta1.Rule{
+						{Name: "admin", IPGroupSelector: []string{"admin"}},
+						{Name: "public", IPGroupSelector: []string{"public"}},
+						{Name: "vpn", IPGroupSelector: []string{"vpn"}},
+					},
+					IPGroups: []beta1.InlineIPGroup{
+						group("admin", "192.169.0.1/32"),
+						group("public", "0.0.0.0/0"),
+					},
+				},
+			}
+		}
+
+		It("should report the rules using groups that violate the policy, whether they are active or not", func() {
+			c := config()
+			c.Spec.IPGroups[1].NotBefore = &metav1.Time{Time: metav1.Now().AddDate(1, 0, 0)}
+			violations := policyViolations(c, nil)
+			Expect(violations).To(HaveLen(1))
+			Expect(violations).To(HaveKey("public"))
+			Expect(formatViolations(violations)).To(ContainSubstring("rule public: cidr 0.0.0.0/0 overlaps the forbidden cidr 10.0.0.0/8"))
+		})
+
+		It("should reject IPGroups only when a rule using them would violate the policy", func() {
+			ctx := context.Background()
+			testScheme := runtime.NewScheme()
+			Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+			v := &PolicyValidator{
+				Client:            fake.NewClientBuilder().WithScheme(testScheme).WithObjects(config()).Build(),
+				IPWhitelistConfig: "ruleset",
+			}
+			vpn := &beta1.IPGroup{ObjectMeta: metav1.ObjectMeta{Name: "vpn"}, Spec: beta1.IPGroupSpec{CIDRS: []beta1.CIDR{{CIDR: "172.16.0.0/12"}}}}
+			_, err := v.ValidateCreate(ctx, vpn)
+			Expect(err).ToNot(HaveOccurred())
+
+			// the forbidden cidr split up into halves is refused all the same
+			vpn.Spec.CIDRS = append(vpn.Spec.CIDRS, beta1.CIDR{CIDR: "10.0.0.0/9"}, beta1.CIDR{CIDR: "10.128.0.0/9"})
+			_, err = v.ValidateUpdate(ctx, nil, vpn)
+			Expect(err).To(MatchError(ContainSubstring("rule vpn: cidrs 10.0.0.0/9, 10.128.0.0/9 overlap the forbidden cidr 10.0.0.0/8")))
+			Expect(err).ToNot(MatchError(ContainSubstring("rule public")))
+
+			_, err = v.ValidateCreate(ctx, config())
+			Expect(err).To(MatchError(ContainSubstring("rule public")))
+		})
+	})
+})
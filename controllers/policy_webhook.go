@@ -0,0 +1,104 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// +kubebuilder:webhook:path=/validate-ingress-security-moulick-v1beta1-ipwhitelistconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=ingress.security.moulick,resources=ipwhitelistconfigs,verbs=create;update,versions=v1beta1,name=vipwhitelistconfig.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-ingress-security-moulick-v1beta1-ipgroup,mutating=false,failurePolicy=fail,sideEffects=None,groups=ingress.security.moulick,resources=ipgroups,verbs=create;update,versions=v1beta1,name=vipgroup.kb.io,admissionReviewVersions=v1
+
+// PolicyValidator rejects changes to the IPWhitelistConfig and to IPGroups that would make a rule violate the policy,
+// the same checks are done again when reconciling in case the webhook is not installed
+type PolicyValidator struct {
+	client.Client
+	IPWhitelistConfig string
+}
+
+// SetupWebhookWithManager registers the validating webhooks with the Manager.
+func (v *PolicyValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).For(&beta1.IPWhitelistConfig{}).WithValidator(v).Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).For(&beta1.IPGroup{}).WithValidator(v).Complete()
+}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *PolicyValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *PolicyValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator, removing a group can not make a rule violate the policy
+func (v *PolicyValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *PolicyValidator) validate(ctx context.Context, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *beta1.IPWhitelistConfig:
+		if o.Name != v.IPWhitelistConfig {
+			// only the configured IPWhitelistConfig is ever applied
+			return nil
+		}
+		objects, err := listIPGroups(ctx, v)
+		if err != nil {
+			return err
+		}
+		if violations := policyViolations(o, objects); len(violations) > 0 {
+			return fmt.Errorf("rules violate the policy: %s", formatViolations(violations))
+		}
+	case *beta1.IPGroup:
+		config := &beta1.IPWhitelistConfig{}
+		if err := v.Get(ctx, client.ObjectKey{Name: v.IPWhitelistConfig}, config); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		objects, err := listIPGroups(ctx, v)
+		if err != nil {
+			return err
+		}
+		objects = slices.DeleteFunc(objects, func(object beta1.IPGroup) bool { return object.Name == o.Name })
+		objects = append(objects, *o)
+		groups := indexIPGroups(mergeIPGroups(config.Spec.IPGroups, objects))
+
+		// only the rules using the group are checked, so that the group can still be changed while another rule is broken
+		violations := policyViolations(config, objects)
+		for _, rule := range config.Spec.Rules {
+			if uses, err := ruleUsesIPGroup(rule, o.Name, objects, groups); err == nil && !uses {
+				delete(violations, rule.Name)
+			}
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("rules using the ipGroup would violate the policy: %s", formatViolations(violations))
+		}
+	}
+	return nil
+}
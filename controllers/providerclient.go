@@ -0,0 +1,252 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// defaultProviderTimeout bounds a fetch of a provider without a timeout of its own, so that a hung upstream does not
+// block a reconcile worker
+const defaultProviderTimeout = 30 * time.Second
+
+// providerTimeout returns how long a fetch of the provider may take, retries included
+func providerTimeout(provider beta1.Providers) time.Duration {
+	if provider.HTTPClient != nil && provider.HTTPClient.Timeout != nil && provider.HTTPClient.Timeout.Duration > 0 {
+		return provider.HTTPClient.Timeout.Duration
+	}
+	return defaultProviderTimeout
+}
+
+// providerClients keeps the http client of every provider by name, so that connections are reused between fetches.
+// A client is rebuilt when its spec, CA bundle or client certificate change. The zero value is ready to use.
+type providerClients struct {
+	mu      sync.Mutex
+	clients map[string]providerClientEntry
+}
+
+type providerClientEntry struct {
+	// key is the hash of everything the client was built from
+	key    string
+	client *http.Client
+}
+
+// get returns the client of the provider built from key, calling build if there is none yet
+func (c *providerClients) get(name, key string, build func() (*http.Client, error)) (*http.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.clients[name]
+	if ok && entry.key == key {
+		return entry.client, nil
+	}
+	client, err := build()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		entry.client.CloseIdleConnections()
+	}
+	if c.clients == nil {
+		c.clients = make(map[string]providerClientEntry)
+	}
+	c.clients[name] = providerClientEntry{key: key, client: client}
+	return client, nil
+}
+
+// providerClient returns the http client for the provider, the shared providerHTTPClient if it has no httpClient
+func (r *IPWhitelistConfigReconciler) providerClient(ctx context.Context, provider beta1.Providers) (*http.Client, error) {
+	spec := provider.HTTPClient
+	if spec == nil {
+		return providerHTTPClient, nil
+	}
+
+	var caBundle, certPEM, keyPEM []byte
+	var err error
+	if spec.CABundle != nil {
+		if caBundle, err = r.readCABundle(ctx, spec.CABundle); err != nil {
+			return nil, err
+		}
+	}
+	if spec.ClientCertificate != nil {
+		secret := &corev1.Secret{}
+		if err = r.Get(ctx, types.NamespacedName{Namespace: spec.ClientCertificate.Namespace, Name: spec.ClientCertificate.Name}, secret); err != nil {
+			return nil, fmt.Errorf("failed to get the client certificate: %w", err)
+		}
+		certPEM, keyPEM = secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	for _, part := range [][]byte{specJSON, caBundle, certPEM, keyPEM} {
+		_, _ = hash.Write(part)
+		_, _ = hash.Write([]byte{0})
+	}
+	return r.httpClients.get(provider.Name, hex.EncodeToString(hash.Sum(nil)), func() (*http.Client, error) {
+		return newProviderClient(spec, caBundle, certPEM, keyPEM)
+	})
+}
+
+// readCABundle reads the PEM encoded CA bundle from its Secret or ConfigMap
+func (r *IPWhitelistConfigReconciler) readCABundle(ctx context.Context, source *beta1.CABundleSource) ([]byte, error) {
+	switch {
+	case source.SecretRef != nil:
+		bundle, err := r.readSecretKey(ctx, source.SecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the caBundle: %w", err)
+		}
+		return []byte(bundle), nil
+	case source.ConfigMapRef != nil:
+		configMap := &corev1.ConfigMap{}
+		ref := source.ConfigMapRef
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ref.ConfigMap.Namespace, Name: ref.ConfigMap.Name}, configMap); err != nil {
+			return nil, fmt.Errorf("failed to read the caBundle: %w", err)
+		}
+		bundle, ok := configMap.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("configMap %s/%s missing key %s", configMap.Namespace, configMap.Name, ref.Key)
+		}
+		return []byte(bundle), nil
+	}
+	return nil, fmt.Errorf("caBundle needs a secretRef or a configMapRef")
+}
+
+// newProviderClient builds the http client of a provider, it propagates the trace context like providerHTTPClient
+func newProviderClient(spec *beta1.ProviderHTTPClient, caBundle, certPEM, keyPEM []byte) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if spec.ProxyURL != "" {
+		proxy, err := url.Parse(spec.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxyURL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if caBundle != nil || certPEM != nil {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if caBundle != nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(caBundle) {
+				return nil, fmt.Errorf("no certificates found in the caBundle")
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if certPEM != nil {
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var roundTripper http.RoundTripper = otelhttp.NewTransport(transport)
+	if spec.Retry != nil && spec.Retry.MaxRetries > 0 {
+		roundTripper = &retryTransport{next: roundTripper, maxRetries: int(spec.Retry.MaxRetries), backoff: spec.Retry.Backoff.Duration}
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// retryTransport retries requests failing with a network error or a status the provider may recover from, with an
+// exponential backoff, or the Retry-After of the response if it is longer. It never waits past the deadline of the
+// request, and then returns the last failure instead.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	backoff    time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		retryable := (err != nil && ctx.Err() == nil) || (resp != nil && unavailableStatus(resp.StatusCode))
+		// a request with a body can only be sent again if the body can be read again
+		if attempt >= t.maxRetries || !retryable || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		wait := t.backoff << attempt
+		if resp != nil {
+			if after := retryAfter(resp.Header.Get("Retry-After"), time.Now()); after > wait {
+				wait = after
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxProviderResponseBytes))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// retryAfter returns the wait asked for by a Retry-After header, either in seconds or as an http date
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
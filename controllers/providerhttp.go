@@ -0,0 +1,115 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxProviderResponseBytes is the largest response body read from a provider, the lists of all providers are far smaller
+const maxProviderResponseBytes = 10 << 20
+
+// providerErrorKind tells the reason a provider failed apart, for the metrics and events
+type providerErrorKind string
+
+const (
+	// providerUnavailable is a provider that could not be reached, or answered that it is not able to serve right now
+	providerUnavailable providerErrorKind = "Unavailable"
+	// providerBadResponse is a provider that answered, but not with the data we expected
+	providerBadResponse providerErrorKind = "BadResponse"
+	// providerUnknownError is any other error, like from the akamai client which does not tell them apart
+	providerUnknownError providerErrorKind = "Unknown"
+)
+
+// providerError is an error of a provider along with its kind
+type providerError struct {
+	kind providerErrorKind
+	err  error
+}
+
+func (e *providerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.kind, e.err)
+}
+
+func (e *providerError) Unwrap() error {
+	return e.err
+}
+
+// unavailable returns an error of the kind providerUnavailable
+func unavailable(format string, args ...any) error {
+	return &providerError{kind: providerUnavailable, err: fmt.Errorf(format, args...)}
+}
+
+// badResponse returns an error of the kind providerBadResponse
+func badResponse(format string, args ...any) error {
+	return &providerError{kind: providerBadResponse, err: fmt.Errorf(format, args...)}
+}
+
+// errorKind returns the kind of the provider error
+func errorKind(err error) providerErrorKind {
+	var perr *providerError
+	if errors.As(err, &perr) {
+		return perr.kind
+	}
+	return providerUnknownError
+}
+
+// unavailableStatus tells whether the status is a provider not able to serve right now, which it may recover from
+func unavailableStatus(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests || code == http.StatusRequestTimeout
+}
+
+// readProviderResponse returns the body of a successful json response of a provider. Server errors and rate limits
+// are reported as providerUnavailable, anything else not looking like the json we asked for as providerBadResponse.
+func readProviderResponse(resp *http.Response) ([]byte, error) {
+	return readProviderBody(resp, false)
+}
+
+// readProviderFile is readProviderResponse for a json document downloaded as a file, which may be served as
+// application/octet-stream
+func readProviderFile(resp *http.Response) ([]byte, error) {
+	return readProviderBody(resp, true)
+}
+
+func readProviderBody(resp *http.Response, file bool) ([]byte, error) {
+	switch {
+	case unavailableStatus(resp.StatusCode):
+		return nil, unavailable("unexpected status %s", resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, badResponse("unexpected status %s", resp.Status)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || (file && mediaType == "application/octet-stream")
+	if err != nil || !isJSON {
+		return nil, badResponse("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProviderResponseBytes+1))
+	if err != nil {
+		return nil, unavailable("failed to read response body: %v", err)
+	}
+	if len(body) > maxProviderResponseBytes {
+		return nil, badResponse("response body larger than %d bytes", maxProviderResponseBytes)
+	}
+	return body, nil
+}
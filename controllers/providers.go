@@ -0,0 +1,320 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"inet.af/netaddr"
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const (
+	reasonProviderSkipped    = "ProviderSkipped"
+	reasonProviderLastKnown  = "ProviderLastKnownGood"
+	reasonProvidersDegraded  = "ProvidersDegraded"
+	reasonProvidersAvailable = "ProvidersAvailable"
+)
+
+// errProviderSkipped is returned for a provider left out of the whitelist by its Skip failurePolicy
+var errProviderSkipped = errors.New("provider skipped")
+
+// providerFetch is the outcome of the last fetch of a provider
+type providerFetch struct {
+	// spec is the provider the cidrs were fetched for, so that a changed provider does not use the cidrs of the old one
+	spec beta1.Providers
+	// cidrs of the last successful fetch
+	cidrs []netaddr.IPPrefix
+	// tags of the cidrs of the last successful fetch
+	tags cidrTags
+	// fetched is the time of the last successful fetch, zero if there was none yet
+	fetched time.Time
+	// err of the last fetch, nil if it succeeded
+	err error
+}
+
+// providerCache keeps the outcome of the last fetch of every provider by name, the zero value is ready to use
+type providerCache struct {
+	mu      sync.Mutex
+	fetches map[string]providerFetch
+}
+
+// get returns the last fetch of the provider, if there was one for the same spec
+func (c *providerCache) get(provider beta1.Providers) (providerFetch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fetch, ok := c.fetches[provider.Name]
+	if !ok || !reflect.DeepEqual(fetch.spec, provider) {
+		return providerFetch{}, false
+	}
+	return fetch, true
+}
+
+// restore sets the cidrs of the last successful fetch of the provider, like from its snapshot after a restart, without
+// changing the outcome of its last fetch
+func (c *providerCache) restore(provider beta1.Providers, cidrs []netaddr.IPPrefix, tags cidrTags, fetched time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetches == nil {
+		c.fetches = make(map[string]providerFetch)
+	}
+	fetch, ok := c.fetches[provider.Name]
+	if !ok || !reflect.DeepEqual(fetch.spec, provider) {
+		fetch = providerFetch{spec: provider}
+	}
+	fetch.cidrs = cidrs
+	fetch.tags = tags
+	fetch.fetched = fetched
+	c.fetches[provider.Name] = fetch
+}
+
+// record stores the outcome of a fetch, a failed fetch keeps the cidrs of the last successful one
+func (c *providerCache) record(provider beta1.Providers, cidrs []netaddr.IPPrefix, tags cidrTags, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetches == nil {
+		c.fetches = make(map[string]providerFetch)
+	}
+	fetch, ok := c.fetches[provider.Name]
+	if !ok || !reflect.DeepEqual(fetch.spec, provider) {
+		fetch = providerFetch{spec: provider}
+	}
+	fetch.err = err
+	if err == nil {
+		fetch.cidrs = cidrs
+		fetch.tags = tags
+		fetch.fetched = now
+	}
+	c.fetches[provider.Name] = fetch
+}
+
+// fetchProvider fetches the current cidrs of the provider, along with their tags
+func (r *IPWhitelistConfigReconciler) fetchProvider(ctx context.Context, provider beta1.Providers) ([]netaddr.IPPrefix, cidrTags, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout(provider))
+	defer cancel()
+	httpClient, err := r.providerClient(ctx, provider)
+	if err != nil {
+		return nil, cidrTags{}, fmt.Errorf("failed to set up the http client of %s: %w", provider.Name, err)
+	}
+
+	var cidrs []netaddr.IPPrefix
+	switch provider.Type {
+	case beta1.Cloudflare:
+		cidrs, err = getCloudFlareCidrs(ctx, httpClient, r.responses.entry(provider.Name), provider.Cloudflare)
+		return cidrs, cidrTags{}, err
+	case beta1.Github:
+		var token string
+		if provider.Github.TokenSecretRef != nil {
+			if token, err = r.readSecretKey(ctx, provider.Github.TokenSecretRef); err != nil {
+				return nil, cidrTags{}, fmt.Errorf("failed to read the token of %s: %w", provider.Name, err)
+			}
+		}
+		return getGitHubCidrs(ctx, httpClient, r.responses.entry(provider.Name), r.rateLimits.limit(provider.Name), strings.TrimSpace(token), provider.Github)
+	case beta1.Akamai:
+		maps, err := r.getAkamaiMaps(ctx, httpClient, provider.Akamai)
+		if err != nil {
+			return nil, cidrTags{}, err
+		}
+		r.siteShieldMaps.set(provider.Name, maps)
+		cidrs, tags := siteShieldCIDRs(maps, provider.Akamai.Transition)
+		return cidrs, tags, nil
+	case beta1.AWS:
+		return getAWSCidrs(ctx, httpClient, r.responses.entry(provider.Name), provider.AWS)
+	case beta1.GCP:
+		return getGCPCidrs(ctx, httpClient, r.responses.entry(provider.Name), provider.GCP)
+	case beta1.Azure:
+		if provider.Azure == nil {
+			return nil, cidrTags{}, fmt.Errorf("the azure provider %s has no azure settings", provider.Name)
+		}
+		return getAzureCidrs(ctx, httpClient, r.responses.entry(provider.Name), *provider.Azure)
+	case beta1.Fastly:
+		r.Log.Info("fastly provider not implemented yet")
+		return nil, cidrTags{}, nil
+	}
+	return nil, cidrTags{}, fmt.Errorf("unknown provider type %s", provider.Type)
+}
+
+// providerCIDRs returns the cidrs of the provider selected by the filter of the rule
+func (r *IPWhitelistConfigReconciler) providerCIDRs(ctx context.Context, config *beta1.IPWhitelistConfig, ing *knet.Ingress, provider beta1.Providers, filter *beta1.ProviderFilter) ([]netaddr.IPPrefix, error) {
+	cidrs, tags, err := r.allProviderCIDRs(ctx, config, ing, provider)
+	if err != nil {
+		return nil, err
+	}
+	return filterProviderCIDRs(provider, filter, cidrs, tags)
+}
+
+// allProviderCIDRs fetches the cidrs of the provider and applies its failurePolicy when that fails. An error is only
+// returned when the reconcile has to fail, or errProviderSkipped when the provider is left out of the whitelist.
+// A fetch failing the sanity checks of the provider is held back and the last known good cidrs are used instead,
+// whatever the failurePolicy, as skipping the provider would lock out its traffic just the same.
+func (r *IPWhitelistConfigReconciler) allProviderCIDRs(ctx context.Context, config *beta1.IPWhitelistConfig, ing *knet.Ingress, provider beta1.Providers) ([]netaddr.IPPrefix, cidrTags, error) {
+	var cidrs []netaddr.IPPrefix
+	var tags cidrTags
+	var err error
+	if provider.Mode == beta1.ProviderModeSnapshotOnly {
+		if cidrs, tags, err = r.snapshotCIDRs(ctx, provider); err == nil {
+			return cidrs, tags, nil
+		}
+	} else {
+		now := time.Now()
+		cidrs, tags, err = r.fetchProvider(ctx, provider)
+		if err != nil {
+			providerFetchErrors.WithLabelValues(provider.Name, string(errorKind(err))).Inc()
+		}
+		// fastly is not implemented yet and never returns any cidrs
+		if err == nil && provider.Type != beta1.Fastly {
+			previous, _, _, _ := r.lastKnownGood(ctx, provider)
+			err = checkProviderSanity(config, provider, previous, cidrs)
+		}
+		r.providers.record(provider, cidrs, tags, err, now)
+
+		var heldBack *heldBackError
+		if errors.As(err, &heldBack) {
+			providerHeldBack.WithLabelValues(provider.Name).Set(1)
+			// the config is only warned again once a different fetch is held back
+			if r.events.changed(reasonProviderHeldBack+"/"+provider.Name, heldBack.hash) {
+				r.Recorder.Event(config, corev1.EventTypeWarning, reasonProviderHeldBack, err.Error())
+			}
+			return r.useLastKnownGood(ctx, ing, provider, err)
+		}
+		if err == nil {
+			providerHeldBack.WithLabelValues(provider.Name).Set(0)
+			r.events.forget(reasonProviderHeldBack + "/" + provider.Name)
+			// the ingresses are warned again once the provider fails again
+			r.events.forgetOthers(providerEventPrefix(reasonProviderSkipped, provider), nil)
+			r.events.forgetOthers(providerEventPrefix(reasonProviderLastKnown, provider), nil)
+			// the snapshot is only needed once the provider fails, so failing to save it does not fail the reconcile
+			if err := r.saveSnapshot(ctx, provider, cidrs, tags, now); err != nil {
+				r.Log.Error(err, "failed to save the providerSnapshot", "provider", provider.Name)
+			}
+			if provider.Type == beta1.Akamai {
+				// a proposal not acknowledged now is acknowledged on a later fetch, the cidrs are good either way
+				if err := r.reconcileSiteShieldProposals(ctx, config, provider); err != nil {
+					r.Log.Error(err, "failed to reconcile the proposals of the site shield maps", "provider", provider.Name)
+				}
+			}
+			return cidrs, tags, nil
+		}
+	}
+
+	switch provider.FailurePolicy {
+	case beta1.FailurePolicyUseLastKnownGood:
+		return r.useLastKnownGood(ctx, ing, provider, err)
+	case beta1.FailurePolicySkip:
+		if r.events.changed(providerEventKey(reasonProviderSkipped, provider, ing), err.Error()) {
+			r.Recorder.Eventf(ing, corev1.EventTypeWarning, reasonProviderSkipped, "provider %s failed and is left out of the whitelist: %s", provider.Name, err)
+		}
+		return nil, cidrTags{}, fmt.Errorf("%w: %s", errProviderSkipped, provider.Name)
+	default:
+		return nil, cidrTags{}, fmt.Errorf("failed to get cidrs from %s: %w", provider.Name, err)
+	}
+}
+
+// lastKnownGood returns the cidrs of the last successful fetch of the provider, their tags and when they were fetched,
+// falling back to its snapshot when nothing was fetched since the operator started
+func (r *IPWhitelistConfigReconciler) lastKnownGood(ctx context.Context, provider beta1.Providers) ([]netaddr.IPPrefix, cidrTags, time.Time, bool) {
+	if fetch, ok := r.providers.get(provider); ok && !fetch.fetched.IsZero() {
+		return fetch.cidrs, fetch.tags, fetch.fetched, true
+	}
+	snapshot, cidrs, tags, err := r.loadSnapshot(ctx, provider)
+	if err != nil || snapshot == nil {
+		return nil, cidrTags{}, time.Time{}, false
+	}
+	r.providers.restore(provider, cidrs, tags, snapshot.Spec.FetchedAt.Time)
+	return cidrs, tags, snapshot.Spec.FetchedAt.Time, true
+}
+
+// useLastKnownGood returns the last known good cidrs of the provider in place of a failed fetch
+func (r *IPWhitelistConfigReconciler) useLastKnownGood(ctx context.Context, ing *knet.Ingress, provider beta1.Providers, err error) ([]netaddr.IPPrefix, cidrTags, error) {
+	cidrs, tags, fetched, ok := r.lastKnownGood(ctx, provider)
+	if !ok {
+		return nil, cidrTags{}, fmt.Errorf("failed to get cidrs from %s and there are no last known good cidrs: %w", provider.Name, err)
+	}
+	message := fmt.Sprintf("provider %s failed, using the cidrs fetched at %s: %s", provider.Name, fetched.UTC().Format(time.RFC3339), err)
+	if r.events.changed(providerEventKey(reasonProviderLastKnown, provider, ing), message) {
+		r.Recorder.Event(ing, corev1.EventTypeWarning, reasonProviderLastKnown, message)
+	}
+	return cidrs, tags, nil
+}
+
+// providerEventKey keys a warning about a failing provider raised on an ingress, it is only raised again on the
+// ingress once the failure changes or the provider recovered in between
+func providerEventKey(reason string, provider beta1.Providers, ing *knet.Ingress) string {
+	return providerEventPrefix(reason, provider) + client.ObjectKeyFromObject(ing).String()
+}
+
+// providerEventPrefix is the prefix of the keys of the warnings about a failing provider raised on all the ingresses
+func providerEventPrefix(reason string, provider beta1.Providers) string {
+	return reason + "/" + provider.Name + " "
+}
+
+// snapshotCIDRs returns the cidrs of the ProviderSnapshot of a provider in the snapshotOnly mode
+func (r *IPWhitelistConfigReconciler) snapshotCIDRs(ctx context.Context, provider beta1.Providers) ([]netaddr.IPPrefix, cidrTags, error) {
+	snapshot, cidrs, tags, err := r.loadSnapshot(ctx, provider)
+	if err == nil && snapshot == nil {
+		err = fmt.Errorf("no providerSnapshot %s with the source %q", provider.Name, providerSource(provider))
+	}
+	if err != nil {
+		r.providers.record(provider, nil, cidrTags{}, err, time.Now())
+		return nil, cidrTags{}, fmt.Errorf("failed to get cidrs of %s from its snapshot: %w", provider.Name, err)
+	}
+	r.providers.record(provider, cidrs, tags, nil, snapshot.Spec.FetchedAt.Time)
+	return cidrs, tags, nil
+}
+
+// updateProviderStatus sets the ProvidersDegraded condition on the config from the last fetch of its providers
+func (r *IPWhitelistConfigReconciler) updateProviderStatus(ctx context.Context, config *beta1.IPWhitelistConfig) error {
+	var degraded []string
+	for _, provider := range config.Spec.Providers {
+		fetch, ok := r.providers.get(provider)
+		if !ok || fetch.err == nil {
+			continue
+		}
+		message := fmt.Sprintf("%s (%s, %s)", provider.Name, provider.FailurePolicy, fetch.err)
+		if provider.FailurePolicy == beta1.FailurePolicyUseLastKnownGood && !fetch.fetched.IsZero() {
+			message = fmt.Sprintf("%s (%s from %s, %s)", provider.Name, provider.FailurePolicy, fetch.fetched.UTC().Format(time.RFC3339), fetch.err)
+		}
+		degraded = append(degraded, message)
+	}
+	sort.Strings(degraded)
+
+	condition := metav1.Condition{
+		Type:    beta1.ConditionProvidersDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonProvidersAvailable,
+		Message: "all providers fetched successfully",
+	}
+	if len(degraded) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonProvidersDegraded
+		condition.Message = "failed to fetch the providers: " + strings.Join(degraded, ", ")
+	}
+	_, err := r.setConfigCondition(ctx, config, condition)
+	return err
+}
@@ -0,0 +1,662 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"inet.af/netaddr"
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("Provider failure policy", func() {
+	ctx := context.Background()
+	ing := &knet.Ingress{}
+	config := &beta1.IPWhitelistConfig{ObjectMeta: metav1.ObjectMeta{Name: "ruleset"}}
+	var healthy, truncated atomic.Bool
+	var server *httptest.Server
+	var r *IPWhitelistConfigReconciler
+	var c client.Client
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(testScheme).Build()
+		healthy.Store(true)
+		truncated.Store(false)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte("upstream connect error"))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if truncated.Load() {
+				_, _ = w.Write([]byte(`{"result": {"ipv4_cidrs": ["173.245.48.0/20"]}, "success": true}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"result": {"ipv4_cidrs": ["173.245.48.0/20", "103.21.244.0/22"]}, "success": true}`))
+		}))
+		r = &IPWhitelistConfigReconciler{Client: c, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	provider := func(policy beta1.ProviderFailurePolicy) beta1.Providers {
+		return beta1.Providers{
+			Name:          "cloudflare",
+			Type:          beta1.Cloudflare,
+			Cloudflare:    beta1.CloudflareProvider{JsonApi: server.URL},
+			FailurePolicy: policy,
+		}
+	}
+
+	It("should fail the reconcile with Fail", func() {
+		healthy.Store(false)
+		_, err := r.providerCIDRs(ctx, config, ing, provider(beta1.FailurePolicyFail), nil)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should use the last fetched cidrs with UseLastKnownGood", func() {
+		p := provider(beta1.FailurePolicyUseLastKnownGood)
+		cidrs, err := r.providerCIDRs(ctx, config, ing, p, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(2))
+
+		healthy.Store(false)
+		lastKnown, err := r.providerCIDRs(ctx, config, ing, p, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lastKnown).To(Equal(cidrs))
+		fetch, ok := r.providers.get(p)
+		Expect(ok).To(BeTrue())
+		Expect(fetch.err).To(HaveOccurred())
+
+		By("not using the cidrs of the provider before it was changed")
+		p.Cloudflare.JsonApi += "/v2"
+		_, err = r.providerCIDRs(ctx, config, ing, p, nil)
+		Expect(err).To(MatchError(ContainSubstring("no last known good cidrs")))
+	})
+	It("should leave the provider out with Skip", func() {
+		healthy.Store(false)
+		cidrs, err := r.providerCIDRs(ctx, config, ing, provider(beta1.FailurePolicySkip), nil)
+		Expect(err).To(MatchError(errProviderSkipped))
+		Expect(cidrs).To(BeEmpty())
+		events := r.Recorder.(*record.FakeRecorder).Events
+		Expect(events).To(Receive(ContainSubstring(reasonProviderSkipped)))
+
+		By("warning the ingress once while the provider keeps failing the same")
+		_, err = r.providerCIDRs(ctx, config, ing, provider(beta1.FailurePolicySkip), nil)
+		Expect(err).To(MatchError(errProviderSkipped))
+		Expect(events).ToNot(Receive())
+
+		By("warning it again once the provider failed again after recovering")
+		healthy.Store(true)
+		_, err = r.providerCIDRs(ctx, config, ing, provider(beta1.FailurePolicySkip), nil)
+		Expect(err).ToNot(HaveOccurred())
+		healthy.Store(false)
+		_, err = r.providerCIDRs(ctx, config, ing, provider(beta1.FailurePolicySkip), nil)
+		Expect(err).To(MatchError(errProviderSkipped))
+		Expect(events).To(Receive(ContainSubstring(reasonProviderSkipped)))
+	})
+	It("should leave the whitelist as it is when a skipped provider is the only source of the rule", func() {
+		healthy.Store(false)
+		config := &beta1.IPWhitelistConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "ruleset"},
+			Spec: beta1.IPWhitelistConfigSpec{
+				WhitelistAnnotation: "ingress.kubernetes.io/whitelist-source-range",
+				Rules: []beta1.Rule{{
+					Name:             "cloudflare",
+					Selector:         &metav1.LabelSelector{MatchLabels: map[string]string{"ipwhitelist-type": "cloudflare"}},
+					ProviderSelector: []beta1.ProviderSelector{{Name: "cloudflare"}},
+				}},
+				Providers: []beta1.Providers{provider(beta1.FailurePolicySkip)},
+			},
+		}
+		ingress := func(name string, annotations map[string]string) *knet.Ingress {
+			return &knet.Ingress{ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Labels:      map[string]string{"ipwhitelist-type": "cloudflare"},
+				Annotations: annotations,
+			}}
+		}
+		whitelisted := ingress("web", map[string]string{config.Spec.WhitelistAnnotation: "173.245.48.0/20,103.21.244.0/22"})
+		added := ingress("new", nil)
+		testScheme := runtime.NewScheme()
+		Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+		Expect(knet.AddToScheme(testScheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&beta1.IPWhitelistConfig{}).WithObjects(config, whitelisted, added).Build()
+		r := &IPWhitelistConfigReconciler{Client: c, IPWhitelistConfig: "ruleset", RequeueInterval: time.Hour, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(whitelisted)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(whitelisted), whitelisted)).To(Succeed())
+		Expect(whitelisted.Annotations).To(HaveKeyWithValue(config.Spec.WhitelistAnnotation, "173.245.48.0/20,103.21.244.0/22"))
+
+		By("denying all on an ingress without a whitelist yet")
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(added)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(added), added)).To(Succeed())
+		Expect(added.Annotations).To(HaveKeyWithValue(config.Spec.WhitelistAnnotation, denyAllWhitelist))
+	})
+
+	Context("When the cidrs are persisted as a ProviderSnapshot", func() {
+		It("should save every successful fetch and use it after a restart", func() {
+			p := provider(beta1.FailurePolicyUseLastKnownGood)
+			_, err := r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			snapshot := &beta1.ProviderSnapshot{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: "cloudflare"}, snapshot)).To(Succeed())
+			Expect(snapshot.Spec.CIDRs).To(Equal([]string{"173.245.48.0/20", "103.21.244.0/22"}))
+			Expect(snapshot.Spec.Source).To(Equal(server.URL))
+			Expect(snapshot.Spec.Hash).To(Equal(snapshotHash(snapshot.Spec.CIDRs)))
+
+			By("restarting while the provider is down")
+			healthy.Store(false)
+			restarted := &IPWhitelistConfigReconciler{Client: c, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+			cidrs, err := restarted.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(HaveLen(2))
+		})
+		It("should only use the snapshot in the snapshotOnly mode", func() {
+			healthy.Store(false)
+			p := provider(beta1.FailurePolicyFail)
+			p.Mode = beta1.ProviderModeSnapshotOnly
+			_, err := r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).To(MatchError(ContainSubstring("no providerSnapshot cloudflare")))
+
+			snapshot := &beta1.ProviderSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "cloudflare"},
+				Spec: beta1.ProviderSnapshotSpec{
+					Type:      beta1.Cloudflare,
+					Source:    server.URL,
+					FetchedAt: metav1.Now(),
+					CIDRs:     []string{"10.0.0.0/8"},
+				},
+			}
+			Expect(c.Create(ctx, snapshot)).To(Succeed())
+			cidrs, err := r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(HaveLen(1))
+			Expect(cidrs[0].String()).To(Equal("10.0.0.0/8"))
+
+			By("refusing a snapshot not matching its hash")
+			snapshot.Spec.Hash = snapshotHash([]string{"10.0.0.0/16"})
+			Expect(c.Update(ctx, snapshot)).To(Succeed())
+			_, err = r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).To(MatchError(ContainSubstring("does not match its hash")))
+		})
+	})
+
+	Context("When a fetch looks broken", func() {
+		It("should hold it back until it is acknowledged", func() {
+			minEntries := int32(2)
+			p := provider(beta1.FailurePolicySkip)
+			p.MinEntries = &minEntries
+			cidrs, err := r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(HaveLen(2))
+
+			truncated.Store(true)
+			heldBack, err := r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(heldBack).To(Equal(cidrs))
+			Expect(r.Recorder.(*record.FakeRecorder).Events).To(Receive(ContainSubstring(reasonProviderHeldBack)))
+
+			By("not warning again while the same fetch is held back")
+			events := r.Recorder.(*record.FakeRecorder).Events
+			Expect(events).To(Receive(ContainSubstring(reasonProviderLastKnown)))
+			_, err = r.providerCIDRs(ctx, config, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).ToNot(Receive())
+
+			By("applying it once acknowledged")
+			acknowledged := config.DeepCopy()
+			acknowledged.Annotations = map[string]string{beta1.AcknowledgeProviderAnnotationPrefix + "cloudflare": snapshotHash([]string{"173.245.48.0/20"})}
+			applied, err := r.providerCIDRs(ctx, acknowledged, ing, p, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(applied).To(HaveLen(1))
+		})
+		It("should hold back fetches shrinking too much", func() {
+			maxShrink := int32(50)
+			p := provider(beta1.FailurePolicyFail)
+			p.MaxShrinkPercent = &maxShrink
+			previous := []netaddr.IPPrefix{netaddr.MustParseIPPrefix("10.0.0.0/8"), netaddr.MustParseIPPrefix("10.1.0.0/16"), netaddr.MustParseIPPrefix("10.2.0.0/16")}
+			Expect(checkProviderSanity(config, p, previous, previous[:2])).To(Succeed())
+			err := checkProviderSanity(config, p, previous, previous[:1])
+			Expect(err).To(MatchError(ContainSubstring("shrank from 3 to 1 cidrs by 66%")))
+			Expect(err).To(MatchError(ContainSubstring(beta1.AcknowledgeProviderAnnotationPrefix + "cloudflare=" + snapshotHash([]string{"10.0.0.0/8"}))))
+		})
+	})
+})
+
+var _ = Describe("Provider responses", func() {
+	response := func(status int, contentType, body string) *http.Response {
+		recorder := httptest.NewRecorder()
+		if contentType != "" {
+			recorder.Header().Set("Content-Type", contentType)
+		}
+		recorder.WriteHeader(status)
+		_, _ = recorder.WriteString(body)
+		return recorder.Result()
+	}
+
+	It("should tell an unavailable provider from a bad response", func() {
+		_, err := readProviderResponse(response(http.StatusServiceUnavailable, "text/html", "<html>down</html>"))
+		Expect(errorKind(err)).To(Equal(providerUnavailable))
+		_, err = readProviderResponse(response(http.StatusTooManyRequests, "application/json", "{}"))
+		Expect(errorKind(err)).To(Equal(providerUnavailable))
+		_, err = readProviderResponse(response(http.StatusNotFound, "application/json", "{}"))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+		Expect(errorKind(errors.New("siteshield"))).To(Equal(providerUnknownError))
+	})
+	It("should only accept json", func() {
+		_, err := readProviderResponse(response(http.StatusOK, "text/html; charset=utf-8", "<html>login</html>"))
+		Expect(err).To(MatchError(ContainSubstring("unexpected content type")))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+		body, err := readProviderResponse(response(http.StatusOK, "application/json; charset=utf-8", "{}"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("{}"))
+	})
+	It("should limit the size of the body", func() {
+		_, err := readProviderResponse(response(http.StatusOK, "application/json", strings.Repeat(" ", maxProviderResponseBytes+1)))
+		Expect(err).To(MatchError(ContainSubstring("larger than")))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+	})
+	It("should validate the cloudflare envelope", func() {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		}))
+		defer server.Close()
+		provider := beta1.CloudflareProvider{JsonApi: server.URL}
+
+		body = `{"result": {"ipv4_cidrs": ["173.245.48.0/20"]}, "success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`
+		_, err := getCloudFlareCidrs(context.Background(), providerHTTPClient, nil, provider)
+		Expect(err).To(MatchError(ContainSubstring("Authentication error")))
+		Expect(errorKind(err)).To(Equal(providerBadResponse))
+
+		body = `{"result": {"ipv4_cidrs": []}, "success": true}`
+		_, err = getCloudFlareCidrs(context.Background(), providerHTTPClient, nil, provider)
+		Expect(err).To(MatchError(ContainSubstring("no ipv4 cidrs")))
+
+		body = `{"result": {"ipv4_cidrs": ["173.245.48.0/20"]}, "success": true}`
+		cidrs, err := getCloudFlareCidrs(context.Background(), providerHTTPClient, nil, provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(1))
+	})
+	It("should only give a site shield map with a deadline its acknowledgeRequiredBy", func() {
+		siteMap, err := siteShieldMapResponse{ID: 1234, CurrentCIDRs: []string{"23.48.168.0/22"}}.toMap()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(siteMap.AcknowledgeRequiredBy.IsZero()).To(BeTrue())
+
+		siteMap, err = siteShieldMapResponse{ID: 1234, AcknowledgeRequiredBy: 1675166400000}.toMap()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(siteMap.AcknowledgeRequiredBy).To(BeTemporally("==", time.Date(2023, 1, 31, 12, 0, 0, 0, time.UTC)))
+	})
+})
+
+var _ = Describe("Provider HTTP client", func() {
+	ctx := context.Background()
+	var r *IPWhitelistConfigReconciler
+	var c client.Client
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+		Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(testScheme).Build()
+		r = &IPWhitelistConfigReconciler{Client: c, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	})
+
+	cloudflare := func(url string, httpClient *beta1.ProviderHTTPClient) beta1.Providers {
+		return beta1.Providers{
+			Name:       "cloudflare",
+			Type:       beta1.Cloudflare,
+			Cloudflare: beta1.CloudflareProvider{JsonApi: url},
+			HTTPClient: httpClient,
+		}
+	}
+	cloudflareHandler := func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": {"ipv4_cidrs": ["173.245.48.0/20"]}, "success": true}`))
+	}
+
+	It("should retry a provider that is unavailable", func() {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if calls.Add(1) < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			cloudflareHandler(w, req)
+		}))
+		defer server.Close()
+
+		p := cloudflare(server.URL, &beta1.ProviderHTTPClient{Retry: &beta1.ProviderRetry{MaxRetries: 2, Backoff: metav1.Duration{Duration: time.Millisecond}}})
+		cidrs, _, err := r.fetchProvider(ctx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(1))
+		Expect(calls.Load()).To(Equal(int32(3)))
+
+		By("giving up after maxRetries")
+		calls.Store(-10)
+		_, _, err = r.fetchProvider(ctx, p)
+		Expect(errorKind(err)).To(Equal(providerUnavailable))
+		Expect(calls.Load()).To(Equal(int32(-7)))
+	})
+	It("should not wait for a Retry-After past the timeout", func() {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		p := cloudflare(server.URL, &beta1.ProviderHTTPClient{
+			Timeout: &metav1.Duration{Duration: time.Second},
+			Retry:   &beta1.ProviderRetry{MaxRetries: 3, Backoff: metav1.Duration{Duration: time.Millisecond}},
+		})
+		_, _, err := r.fetchProvider(ctx, p)
+		Expect(err).To(MatchError(ContainSubstring("429")))
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+	It("should time out a hung provider", func() {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		p := cloudflare(server.URL, &beta1.ProviderHTTPClient{Timeout: &metav1.Duration{Duration: 50 * time.Millisecond}})
+		_, _, err := r.fetchProvider(ctx, p)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(errorKind(err)).To(Equal(providerUnavailable))
+	})
+	It("should parse Retry-After in seconds and as a date", func() {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		Expect(retryAfter("120", now)).To(Equal(2 * time.Minute))
+		Expect(retryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)).To(Equal(time.Minute))
+		Expect(retryAfter("soon", now)).To(BeZero())
+	})
+
+	Context("When the provider uses a private CA", func() {
+		var server *httptest.Server
+		var caBundle *beta1.CABundleSource
+
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/siteshield/v1/maps/1234" {
+					Expect(req.Header.Get("Authorization")).To(HavePrefix("EG1-HMAC-SHA256 client_token=token;access_token=access;"))
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`{"id": 1234, "currentCidrs": ["23.48.168.0/22"], "proposedCidrs": []}`))
+					return
+				}
+				cloudflareHandler(w, req)
+			}))
+			ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			Expect(c.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider-ca", Namespace: "default"},
+				Data:       map[string]string{"ca.crt": string(ca)},
+			})).To(Succeed())
+			caBundle = &beta1.CABundleSource{ConfigMapRef: &beta1.ConfigMapKeySelector{
+				ConfigMap: beta1.ConfigMapReference{Name: "provider-ca", Namespace: "default"},
+				Key:       "ca.crt",
+			}}
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should trust the caBundle", func() {
+			_, _, err := r.fetchProvider(ctx, cloudflare(server.URL, nil))
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+
+			cidrs, _, err := r.fetchProvider(ctx, cloudflare(server.URL, &beta1.ProviderHTTPClient{CABundle: caBundle}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(HaveLen(1))
+		})
+		It("should fetch the site shield map with the http client of the provider", func() {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "akamai", Namespace: "default"},
+				Data: map[string][]byte{
+					"host":          []byte(server.Listener.Addr().String()),
+					"client_token":  []byte("token"),
+					"client_secret": []byte("secret"),
+					"access_token":  []byte("access"),
+				},
+			}
+			Expect(c.Create(ctx, secret)).To(Succeed())
+			ref := func(key string) *beta1.SecretKeySelector {
+				return &beta1.SecretKeySelector{Secret: corev1.SecretReference{Name: "akamai", Namespace: "default"}, Key: key}
+			}
+			mapID := intstr.FromInt(1234)
+			p := beta1.Providers{
+				Name: "akamai",
+				Type: beta1.Akamai,
+				Akamai: beta1.AkamaiProvider{
+					MapId:        &mapID,
+					Host:         ref("host"),
+					ClientToken:  ref("client_token"),
+					ClientSecret: ref("client_secret"),
+					AccessToken:  ref("access_token"),
+				},
+				HTTPClient: &beta1.ProviderHTTPClient{CABundle: caBundle},
+			}
+			cidrs, _, err := r.fetchProvider(ctx, p)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("23.48.168.0/22")}))
+		})
+	})
+})
+
+var _ = Describe("Conditional requests", func() {
+	ctx := context.Background()
+	var notModified atomic.Int32
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		notModified.Store(0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("If-None-Match") == `"v1"` || req.Header.Get("If-Modified-Since") == "Sun, 01 Jan 2023 00:00:00 GMT" {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if req.URL.Path == "/meta" {
+				w.Header().Set("Last-Modified", "Sun, 01 Jan 2023 00:00:00 GMT")
+				_, _ = w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["140.82.112.0/20", "143.55.64.0/20"]}`))
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`{"result": {"ipv4_cidrs": ["173.245.48.0/20"]}, "success": true}`))
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should reuse the cidrs of cloudflare when not modified", func() {
+		cached := &cachedResponse{name: "cloudflare"}
+		provider := beta1.CloudflareProvider{JsonApi: server.URL}
+		cidrs, err := getCloudFlareCidrs(ctx, providerHTTPClient, cached, provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(notModified.Load()).To(BeZero())
+
+		again, err := getCloudFlareCidrs(ctx, providerHTTPClient, cached, provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(notModified.Load()).To(Equal(int32(1)))
+		Expect(again).To(Equal(cidrs))
+	})
+	It("should only reuse the cidrs of github for the same services", func() {
+		cached := &cachedResponse{name: "github"}
+		provider := beta1.GithubProvider{JsonApi: server.URL + "/meta", Services: []string{"hooks"}}
+		cidrs, _, err := getGitHubCidrs(ctx, providerHTTPClient, cached, nil, "", provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(1))
+		_, _, err = getGitHubCidrs(ctx, providerHTTPClient, cached, nil, "", provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(notModified.Load()).To(Equal(int32(1)))
+
+		provider.Services = []string{"web"}
+		cidrs, _, err = getGitHubCidrs(ctx, providerHTTPClient, cached, nil, "", provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(2))
+		Expect(notModified.Load()).To(Equal(int32(1)))
+	})
+	It("should only read a versioned document again once its version or the key changes", func() {
+		var version atomic.Int32
+		version.Store(1)
+		versioned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			// a document served as a file, without an ETag or Last-Modified
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(`{"syncToken": "` + strconv.Itoa(int(version.Load())) + `"}`))
+		}))
+		defer versioned.Close()
+		var reads int
+		read := func([]byte) ([]netaddr.IPPrefix, cidrTags, error) {
+			reads++
+			return []netaddr.IPPrefix{netaddr.MustParseIPPrefix("192.0.2.0/24")}, cidrTags{}, nil
+		}
+
+		cached := &cachedResponse{name: "aws"}
+		cidrs, _, err := fetchVersionedDocument(ctx, providerHTTPClient, cached, versioned.URL, "services EC2", "syncToken", read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(1))
+		Expect(cached.version).To(Equal("1"))
+		again, _, err := fetchVersionedDocument(ctx, providerHTTPClient, cached, versioned.URL, "services EC2", "syncToken", read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(Equal(cidrs))
+		Expect(reads).To(Equal(1))
+
+		By("reading it again for another key")
+		_, _, err = fetchVersionedDocument(ctx, providerHTTPClient, cached, versioned.URL, "services S3", "syncToken", read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reads).To(Equal(2))
+
+		By("reading it again once the version changed")
+		version.Store(2)
+		_, _, err = fetchVersionedDocument(ctx, providerHTTPClient, cached, versioned.URL, "services S3", "syncToken", read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reads).To(Equal(3))
+		Expect(cached.version).To(Equal("2"))
+	})
+})
+
+// serveDocument serves the body as the document of a provider
+func serveDocument(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+var _ = Describe("GitHub rate limits", func() {
+	ctx := context.Background()
+	var calls atomic.Int32
+	var remaining atomic.Int32
+	var server *httptest.Server
+	var r *IPWhitelistConfigReconciler
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	BeforeEach(func() {
+		calls.Store(0)
+		remaining.Store(1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls.Add(1)
+			Expect(req.Header.Get("Authorization")).To(Equal("Bearer ghp_token"))
+			left := remaining.Add(-1)
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if left < 0 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(left)))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+		}))
+
+		testScheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("ghp_token\n")},
+		}).Build()
+		r = &IPWhitelistConfigReconciler{Client: c, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	provider := func() beta1.Providers {
+		return beta1.Providers{
+			Name: "github",
+			Type: beta1.Github,
+			Github: beta1.GithubProvider{
+				JsonApi:        server.URL,
+				Services:       []string{"hooks"},
+				TokenSecretRef: &beta1.SecretKeySelector{Secret: corev1.SecretReference{Name: "github", Namespace: "default"}, Key: "token"},
+			},
+		}
+	}
+
+	It("should not request github again until the rate limit resets", func() {
+		_, _, err := r.fetchProvider(ctx, provider())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = r.fetchProvider(ctx, provider())
+		var rateLimited *rateLimitedError
+		Expect(errors.As(err, &rateLimited)).To(BeTrue())
+		Expect(rateLimited.reset).To(BeTemporally("==", reset))
+		Expect(errorKind(err)).To(Equal(providerUnavailable))
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+	It("should back off when github refuses a request", func() {
+		remaining.Store(0)
+		_, _, err := r.fetchProvider(ctx, provider())
+		Expect(err).To(MatchError(ContainSubstring("rate limit of github exhausted")))
+		_, _, err = r.fetchProvider(ctx, provider())
+		Expect(err).To(MatchError(ContainSubstring("rate limit of github exhausted")))
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+})
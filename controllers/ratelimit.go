@@ -0,0 +1,105 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitedError is a provider that used up its rate limit, it is not requested again before the limit resets
+type rateLimitedError struct {
+	provider string
+	reset    time.Time
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit of %s exhausted until %s", e.provider, e.reset.UTC().Format(time.RFC3339))
+}
+
+// rateLimits keeps the rate limit of every provider by name, the zero value is ready to use
+type rateLimits struct {
+	mu     sync.Mutex
+	limits map[string]*rateLimit
+}
+
+// limit returns the rate limit of the provider
+func (l *rateLimits) limit(name string) *rateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limits == nil {
+		l.limits = make(map[string]*rateLimit)
+	}
+	limit, ok := l.limits[name]
+	if !ok {
+		limit = &rateLimit{name: name}
+		l.limits[name] = limit
+	}
+	return limit
+}
+
+// rateLimit is the rate limit of a provider as reported by the X-RateLimit headers of its last response. A nil
+// rateLimit is never exhausted.
+type rateLimit struct {
+	name string
+
+	mu sync.Mutex
+	// reset is the time until which the provider must not be requested, zero if the rate limit is not exhausted
+	reset time.Time
+}
+
+// wait returns a rateLimitedError while the rate limit is exhausted
+func (l *rateLimit) wait(now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Before(l.reset) {
+		return &providerError{kind: providerUnavailable, err: &rateLimitedError{provider: l.name, reset: l.reset}}
+	}
+	return nil
+}
+
+// observe reads the rate limit from the response, and returns a rateLimitedError if the request was refused because
+// of it. A response without X-RateLimit headers leaves the rate limit as it is.
+func (l *rateLimit) observe(resp *http.Response, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		providerRateLimitRemaining.WithLabelValues(l.name).Set(float64(remaining))
+		l.reset = time.Time{}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil && remaining == 0 {
+			l.reset = time.Unix(reset, 0)
+		}
+	}
+	// secondary rate limits are only announced with a Retry-After
+	refused := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+	if after := retryAfter(resp.Header.Get("Retry-After"), now); refused && after > 0 && now.Add(after).After(l.reset) {
+		l.reset = now.Add(after)
+	}
+	if refused && now.Before(l.reset) {
+		return &providerError{kind: providerUnavailable, err: &rateLimitedError{provider: l.name, reset: l.reset}}
+	}
+	return nil
+}
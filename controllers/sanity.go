@@ -0,0 +1,74 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"inet.af/netaddr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const reasonProviderHeldBack = "ProviderHeldBack"
+
+// heldBackError is returned for a fetch that looks broken, like an empty or truncated list, which is only applied once
+// it is acknowledged with its hash
+type heldBackError struct {
+	provider string
+	reason   string
+	hash     string
+}
+
+func (e *heldBackError) Error() string {
+	return fmt.Sprintf("fetch of provider %s held back as %s, annotate the IPWhitelistConfig with %s%s=%s to apply it",
+		e.provider, e.reason, beta1.AcknowledgeProviderAnnotationPrefix, e.provider, e.hash)
+}
+
+// prefixStrings returns the cidrs as strings
+func prefixStrings(cidrs []netaddr.IPPrefix) []string {
+	strs := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		strs = append(strs, cidr.String())
+	}
+	return strs
+}
+
+// checkProviderSanity returns a heldBackError if the fetched cidrs have fewer entries than the minEntries of the
+// provider, or shrank by more than its maxShrinkPercent compared to the previous cidrs, unless the config acknowledges
+// exactly these cidrs
+func checkProviderSanity(config *beta1.IPWhitelistConfig, provider beta1.Providers, previous, cidrs []netaddr.IPPrefix) error {
+	var reason string
+	switch {
+	case provider.MinEntries != nil && len(cidrs) < int(*provider.MinEntries):
+		reason = fmt.Sprintf("it returned %d cidrs, fewer than the minEntries %d", len(cidrs), *provider.MinEntries)
+	case provider.MaxShrinkPercent != nil && len(previous) > 0 && len(cidrs) < len(previous):
+		shrink := (len(previous) - len(cidrs)) * 100 / len(previous)
+		if shrink > int(*provider.MaxShrinkPercent) {
+			reason = fmt.Sprintf("it shrank from %d to %d cidrs by %d%%, more than the maxShrinkPercent %d%%", len(previous), len(cidrs), shrink, *provider.MaxShrinkPercent)
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+
+	hash := snapshotHash(prefixStrings(cidrs))
+	if config.Annotations[beta1.AcknowledgeProviderAnnotationPrefix+provider.Name] == hash {
+		return nil
+	}
+	return &heldBackError{provider: provider.Name, reason: reason, hash: hash}
+}
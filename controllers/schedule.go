@@ -0,0 +1,118 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// occurrence is a single occurrence of a TimeWindow, from start and until, but excluding, end
+type occurrence struct {
+	start, end time.Time
+}
+
+func (o occurrence) contains(t time.Time) bool {
+	return !t.Before(o.start) && t.Before(o.end)
+}
+
+// scheduleOpen returns true if any window of the schedule is open at the given time,
+// along with the next time a window opens or closes, which is zero if that never happens.
+func scheduleOpen(schedule *beta1.Schedule, now time.Time) (bool, time.Time, error) {
+	occurrences, err := scheduleOccurrences(schedule, now)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	open := func(t time.Time) bool {
+		for _, o := range occurrences {
+			if o.contains(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// every window repeats within a week, so the next boundary that flips the state has to be one of these
+	var boundaries []time.Time
+	for _, o := range occurrences {
+		boundaries = append(boundaries, o.start, o.end)
+	}
+	sort.Slice(boundaries, func(a, b int) bool {
+		return boundaries[a].Before(boundaries[b])
+	})
+
+	current := open(now)
+	for _, boundary := range boundaries {
+		if boundary.After(now) && open(boundary) != current {
+			return current, boundary, nil
+		}
+	}
+	return current, time.Time{}, nil
+}
+
+// scheduleOccurrences returns the occurrences of the windows from the day before now until a week after now.
+// The day before is needed for windows that started yesterday and run past midnight.
+func scheduleOccurrences(schedule *beta1.Schedule, now time.Time) ([]occurrence, error) {
+	loc := time.UTC
+	if schedule.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(schedule.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid timeZone %q: %w", schedule.TimeZone, err)
+		}
+	}
+
+	local := now.In(loc)
+	var occurrences []occurrence
+	for _, window := range schedule.Windows {
+		startHour, startMinute, err := parseTimeOfDay(window.Start)
+		if err != nil {
+			return nil, err
+		}
+		endHour, endMinute, err := parseTimeOfDay(window.End)
+		if err != nil {
+			return nil, err
+		}
+		for offset := -1; offset <= 7; offset++ {
+			day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+			if len(window.Days) > 0 && !slices.Contains(window.Days, beta1.Weekday(day.Weekday().String())) {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), startHour, startMinute, 0, 0, loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), endHour, endMinute, 0, 0, loc)
+			if !end.After(start) {
+				// the window runs past midnight
+				end = time.Date(day.Year(), day.Month(), day.Day()+1, endHour, endMinute, 0, 0, loc)
+			}
+			occurrences = append(occurrences, occurrence{start: start, end: end})
+		}
+	}
+
+	return occurrences, nil
+}
+
+// parseTimeOfDay parses a time of the day given as HH:MM
+func parseTimeOfDay(value string) (int, int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q, expected HH:MM: %w", value, err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
@@ -0,0 +1,148 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("IPGroup schedule", func() {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	Expect(err).ToNot(HaveOccurred())
+
+	businessHours := &beta1.Schedule{
+		TimeZone: "Europe/Berlin",
+		Windows: []beta1.TimeWindow{
+			{
+				Days:  []beta1.Weekday{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+				Start: "09:00",
+				End:   "17:00",
+			},
+		},
+	}
+
+	Context("When the schedule has business hours", func() {
+		It("should be open during the window and close at its end", func() {
+			// Wednesday
+			now := time.Date(2023, time.March, 1, 12, 0, 0, 0, berlin)
+			open, next, err := scheduleOpen(businessHours, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeTrue())
+			Expect(next).To(BeTemporally("==", time.Date(2023, time.March, 1, 17, 0, 0, 0, berlin)))
+		})
+		It("should be closed over the weekend and open on monday morning", func() {
+			// Saturday, in UTC to make sure the time zone of the schedule is used
+			now := time.Date(2023, time.March, 4, 12, 0, 0, 0, time.UTC)
+			open, next, err := scheduleOpen(businessHours, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeFalse())
+			Expect(next).To(BeTemporally("==", time.Date(2023, time.March, 6, 9, 0, 0, 0, berlin)))
+		})
+		It("should be open from the exact start and closed from the exact end", func() {
+			open, _, err := scheduleOpen(businessHours, time.Date(2023, time.March, 1, 9, 0, 0, 0, berlin))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeTrue())
+			open, _, err = scheduleOpen(businessHours, time.Date(2023, time.March, 1, 17, 0, 0, 0, berlin))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeFalse())
+		})
+	})
+
+	Context("When a window runs past midnight", func() {
+		batch := &beta1.Schedule{
+			Windows: []beta1.TimeWindow{{Days: []beta1.Weekday{"Sunday"}, Start: "22:00", End: "02:00"}},
+		}
+		It("should still be open on the next day", func() {
+			// Monday 01:00 UTC, the window opened on Sunday
+			open, next, err := scheduleOpen(batch, time.Date(2023, time.March, 6, 1, 0, 0, 0, time.UTC))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeTrue())
+			Expect(next).To(BeTemporally("==", time.Date(2023, time.March, 6, 2, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Context("When the time zone is unknown", func() {
+		It("should return an error", func() {
+			_, _, err := scheduleOpen(&beta1.Schedule{TimeZone: "Mars/Olympus", Windows: businessHours.Windows}, time.Now())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When a group has a schedule", func() {
+		It("should only return the cidrs while the window is open", func() {
+			group := beta1.InlineIPGroup{Name: "vendor", IPGroupSpec: beta1.IPGroupSpec{Schedule: businessHours, CIDRS: []beta1.CIDR{{CIDR: "10.0.0.0/8"}}}}
+			cidrs, next, err := activeCIDRs(group, time.Date(2023, time.March, 4, 12, 0, 0, 0, berlin))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(BeEmpty())
+			Expect(next).To(BeTemporally("==", time.Date(2023, time.March, 6, 9, 0, 0, 0, berlin)))
+
+			cidrs, _, err = activeCIDRs(group, time.Date(2023, time.March, 6, 9, 0, 0, 0, berlin))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"10.0.0.0/8"}))
+		})
+		It("should deny all on an ingress whose rule only has the group, outside of its schedule", func() {
+			ctx := context.Background()
+			// a window on another day than today, so that it is closed whenever the spec runs
+			closed := &beta1.Schedule{TimeZone: "UTC", Windows: []beta1.TimeWindow{{
+				Days:  []beta1.Weekday{beta1.Weekday(time.Now().UTC().AddDate(0, 0, 3).Weekday().String())},
+				Start: "00:00",
+				End:   "00:01",
+			}}}
+			config := &beta1.IPWhitelistConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "ruleset"},
+				Spec: beta1.IPWhitelistConfigSpec{
+					WhitelistAnnotation: "ingress.kubernetes.io/whitelist-source-range",
+					Rules: []beta1.Rule{{
+						Name:            "vendor",
+						Selector:        &metav1.LabelSelector{MatchLabels: map[string]string{"ipwhitelist-type": "vendor"}},
+						IPGroupSelector: []string{"vendor"},
+					}},
+					IPGroups: []beta1.InlineIPGroup{{Name: "vendor", IPGroupSpec: beta1.IPGroupSpec{Schedule: closed, CIDRS: []beta1.CIDR{{CIDR: "10.0.0.0/8"}}}}},
+				},
+			}
+			ing := &knet.Ingress{ObjectMeta: metav1.ObjectMeta{
+				Name:        "support",
+				Namespace:   "default",
+				Labels:      map[string]string{"ipwhitelist-type": "vendor"},
+				Annotations: map[string]string{config.Spec.WhitelistAnnotation: "10.0.0.0/8"},
+			}}
+			testScheme := runtime.NewScheme()
+			Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+			Expect(knet.AddToScheme(testScheme)).To(Succeed())
+			c := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&beta1.IPWhitelistConfig{}).WithObjects(config, ing).Build()
+			r := &IPWhitelistConfigReconciler{Client: c, IPWhitelistConfig: "ruleset", RequeueInterval: time.Hour, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ing)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(ing), ing)).To(Succeed())
+			Expect(ing.Annotations).To(HaveKeyWithValue(config.Spec.WhitelistAnnotation, denyAllWhitelist))
+		})
+	})
+})
@@ -0,0 +1,207 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/corbaltcode/go-akamai"
+	"github.com/corbaltcode/go-akamai/edgegrid"
+	"github.com/corbaltcode/go-akamai/siteshield"
+	jsoniter "github.com/json-iterator/go"
+	"inet.af/netaddr"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const siteShieldBasePath = "/siteshield/v1/"
+
+// siteShieldClient is a client of the Akamai Site Shield API. Unlike the client of go-akamai, which always uses
+// http.DefaultClient without a context, it binds every request to the context and uses the http client of the provider.
+type siteShieldClient struct {
+	credentials akamai.Credentials
+	httpClient  *http.Client
+}
+
+// siteShieldMapResponse is a map as returned by the Site Shield API
+type siteShieldMapResponse struct {
+	AcknowledgeRequiredBy int64    `json:"acknowledgeRequiredBy"`
+	Acknowledged          bool     `json:"acknowledged"`
+	AcknowledgedBy        string   `json:"acknowledgedBy"`
+	Contacts              []string `json:"contacts"`
+	CurrentCIDRs          []string `json:"currentCidrs"`
+	ID                    int      `json:"id"`
+	LatestTicketID        int      `json:"latestTicketId"`
+	MapAlias              string   `json:"mapAlias"`
+	ProposedCIDRs         []string `json:"proposedCidrs"`
+	RuleName              string   `json:"ruleName"`
+	Shared                bool     `json:"shared"`
+	SureRouteName         string   `json:"sureRouteName"`
+	Type                  string   `json:"type"`
+}
+
+// getMap returns the Site Shield map with the id
+func (c *siteShieldClient) getMap(ctx context.Context, id int) (siteshield.Map, error) {
+	var resp siteShieldMapResponse
+	if err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("%smaps/%d", siteShieldBasePath, id), nil, &resp); err != nil {
+		return siteshield.Map{}, err
+	}
+	return resp.toMap()
+}
+
+// getMaps returns all the Site Shield maps visible to the credentials
+func (c *siteShieldClient) getMaps(ctx context.Context) ([]siteshield.Map, error) {
+	var resp struct {
+		SiteShieldMaps []siteShieldMapResponse `json:"siteShieldMaps"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, siteShieldBasePath+"maps", nil, &resp); err != nil {
+		return nil, err
+	}
+	maps := make([]siteshield.Map, 0, len(resp.SiteShieldMaps))
+	for _, m := range resp.SiteShieldMaps {
+		siteMap, err := m.toMap()
+		if err != nil {
+			return nil, err
+		}
+		maps = append(maps, siteMap)
+	}
+	return maps, nil
+}
+
+// acknowledgeMap acknowledges the proposed cidrs of the Site Shield map with the id, Akamai starts moving the map to
+// them once it is acknowledged
+func (c *siteShieldClient) acknowledgeMap(ctx context.Context, id int) (siteshield.Map, error) {
+	var resp siteShieldMapResponse
+	if err := c.doJSON(ctx, http.MethodPost, fmt.Sprintf("%smaps/%d/acknowledge", siteShieldBasePath, id), nil, &resp); err != nil {
+		return siteshield.Map{}, err
+	}
+	return resp.toMap()
+}
+
+// doJSON sends the request signed with EdgeGrid and unmarshals the json response into out
+func (c *siteShieldClient) doJSON(ctx context.Context, method, path string, body []byte, out any) error {
+	const scheme = "https"
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s://%s%s", scheme, c.credentials.Host, path), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("client: could not create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	authHeader, err := edgegrid.GenerateAuthHeader(c.credentials, method, scheme, path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return unavailable("failed to make http call to akamai: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := readProviderResponse(resp)
+	if err != nil {
+		return err
+	}
+	if err := jsoniter.Unmarshal(data, out); err != nil {
+		return badResponse("failed to unmarshal response body from akamai: %v", err)
+	}
+	return nil
+}
+
+// toMap converts the response to the map of the siteshield package
+func (r siteShieldMapResponse) toMap() (siteshield.Map, error) {
+	current, err := parseSiteShieldCIDRs(r.CurrentCIDRs)
+	if err != nil {
+		return siteshield.Map{}, err
+	}
+	proposed, err := parseSiteShieldCIDRs(r.ProposedCIDRs)
+	if err != nil {
+		return siteshield.Map{}, err
+	}
+	// a map without a pending proposal has no deadline, which is left zero rather than the unix epoch
+	var acknowledgeRequiredBy time.Time
+	if r.AcknowledgeRequiredBy != 0 {
+		acknowledgeRequiredBy = time.UnixMilli(r.AcknowledgeRequiredBy)
+	}
+	return siteshield.Map{
+		AcknowledgeRequiredBy: acknowledgeRequiredBy,
+		Acknowledged:          r.Acknowledged,
+		AcknowledgedBy:        r.AcknowledgedBy,
+		Alias:                 r.MapAlias,
+		Contacts:              r.Contacts,
+		CurrentCIDRs:          current,
+		ID:                    r.ID,
+		IsShared:              r.Shared,
+		LatestTicketID:        r.LatestTicketID,
+		ProposedCIDRs:         proposed,
+		RuleName:              r.RuleName,
+		SureRouteName:         r.SureRouteName,
+		Type:                  r.Type,
+	}, nil
+}
+
+func parseSiteShieldCIDRs(cidrs []string) ([]netaddr.IPPrefix, error) {
+	prefixes := make([]netaddr.IPPrefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netaddr.ParseIPPrefix(cidr)
+		if err != nil {
+			return nil, badResponse("unable to parse ip %s: %v", cidr, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// siteShieldMapIDs returns the ids of the maps of the provider, mapId first and without duplicates
+func siteShieldMapIDs(provider beta1.AkamaiProvider) []int {
+	var ids []int
+	ref := provider.MapIds
+	if provider.MapId != nil {
+		ref = append([]intstr.IntOrString{*provider.MapId}, ref...)
+	}
+	for _, id := range ref {
+		if !slices.Contains(ids, id.IntValue()) {
+			ids = append(ids, id.IntValue())
+		}
+	}
+	return ids
+}
+
+// siteShieldCIDRs returns the cidrs to whitelist of all the maps, each tagged with the id of its map as service
+func siteShieldCIDRs(maps []siteshield.Map, transition beta1.SiteShieldTransition) ([]netaddr.IPPrefix, cidrTags) {
+	var cidrs []netaddr.IPPrefix
+	var tags cidrTags
+	for _, siteMap := range maps {
+		for _, cidr := range akamaiCidrs(siteMap, transition) {
+			tags.addService(strconv.Itoa(siteMap.ID), cidr)
+			if !slices.Contains(cidrs, cidr) {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	return cidrs, tags
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corbaltcode/go-akamai/siteshield"
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+const (
+	reasonSiteShieldAcknowledged     = "SiteShieldMapAcknowledged"
+	reasonSiteShieldProposalsPending = "SiteShieldProposalsPending"
+	reasonNoSiteShieldProposals      = "NoSiteShieldProposals"
+)
+
+// siteShieldMaps keeps the last fetched Site Shield maps of every akamai provider by name, the zero value is ready to
+// use
+type siteShieldMaps struct {
+	mu   sync.Mutex
+	maps map[string][]siteshield.Map
+}
+
+func (m *siteShieldMaps) set(name string, maps []siteshield.Map) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.maps == nil {
+		m.maps = make(map[string][]siteshield.Map)
+	}
+	m.maps[name] = slices.Clone(maps)
+}
+
+func (m *siteShieldMaps) get(name string) ([]siteshield.Map, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	maps, ok := m.maps[name]
+	return slices.Clone(maps), ok
+}
+
+// proposalPending returns true if the map has proposed cidrs which are not acknowledged yet
+func proposalPending(siteMap siteshield.Map) bool {
+	return !siteMap.Acknowledged && len(siteMap.ProposedCIDRs) > 0
+}
+
+// reconcileSiteShieldProposals acknowledges the proposals of the last fetched maps of the provider when its acknowledge
+// allows it, and reports the proposals in the status of the config while they are pending
+func (r *IPWhitelistConfigReconciler) reconcileSiteShieldProposals(ctx context.Context, config *beta1.IPWhitelistConfig, provider beta1.Providers) error {
+	maps, ok := r.siteShieldMaps.get(provider.Name)
+	if !ok {
+		return nil
+	}
+	var errs []error
+	for i, siteMap := range maps {
+		if !proposalPending(siteMap) {
+			continue
+		}
+		acknowledge, err := r.shouldAcknowledge(ctx, config, provider, siteMap)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !acknowledge {
+			continue
+		}
+		acknowledged, err := r.acknowledgeSiteShieldMap(ctx, provider, siteMap.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to acknowledge the site shield map %d: %w", siteMap.ID, err))
+			continue
+		}
+		maps[i] = acknowledged
+		r.Recorder.Eventf(config, corev1.EventTypeNormal, reasonSiteShieldAcknowledged, "acknowledged the proposed cidrs of the site shield map %d of provider %s (%s)",
+			siteMap.ID, provider.Name, provider.Akamai.Acknowledge)
+	}
+	r.siteShieldMaps.set(provider.Name, maps)
+	errs = append(errs, r.updateSiteShieldStatus(ctx, config, provider, maps))
+	return errors.Join(errs...)
+}
+
+// shouldAcknowledge returns true if the pending proposal of the map may be acknowledged now
+func (r *IPWhitelistConfigReconciler) shouldAcknowledge(ctx context.Context, config *beta1.IPWhitelistConfig, provider beta1.Providers, siteMap siteshield.Map) (bool, error) {
+	switch provider.Akamai.Acknowledge {
+	case beta1.SiteShieldAcknowledgeAuto:
+		return true, nil
+	case beta1.SiteShieldAcknowledgeAfterApplied:
+		return r.proposalApplied(ctx, config, provider, siteMap)
+	default:
+		return false, nil
+	}
+}
+
+// proposalApplied returns true if every ingress whitelisting the map already carries the proposed cidrs selected by
+// the filter of its rule
+func (r *IPWhitelistConfigReconciler) proposalApplied(ctx context.Context, config *beta1.IPWhitelistConfig, provider beta1.Providers, siteMap siteshield.Map) (bool, error) {
+	var ingresses knet.IngressList
+	if err := r.List(ctx, &ingresses); err != nil {
+		return false, err
+	}
+	for i := range ingresses.Items {
+		ing := &ingresses.Items[i]
+		selector, err := r.ingressProviderSelector(ctx, config, ing, provider.Name)
+		if err != nil {
+			return false, err
+		}
+		if selector == nil {
+			continue
+		}
+		// the maps of the provider are its services, an ingress filtering out the map does not need its cidrs
+		var family *beta1.ProviderFilter
+		if selector.Filter != nil {
+			if len(selector.Filter.Services) > 0 && !slices.Contains(selector.Filter.Services, strconv.Itoa(siteMap.ID)) {
+				continue
+			}
+			family = &beta1.ProviderFilter{IPFamily: selector.Filter.IPFamily}
+		}
+		proposed, err := filterProviderCIDRs(provider, family, siteMap.ProposedCIDRs, cidrTags{})
+		if err != nil {
+			return false, err
+		}
+		whitelist := make(map[string]bool)
+		for _, cidr := range strings.Split(ing.Annotations[config.Spec.WhitelistAnnotation], ",") {
+			whitelist[cidr] = true
+		}
+		for _, cidr := range proposed {
+			if !whitelist[cidr.String()] {
+				r.Log.Info("proposal of the site shield map not applied yet", "provider", provider.Name, "mapId", siteMap.ID, "ingress", client.ObjectKeyFromObject(ing).String(), "cidr", cidr.String())
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// ingressProviderSelector returns the selector of the provider in the rule applied to the ingress, nil if that rule
+// does not whitelist the provider. Like the reconcile, the first rule matching the ingress and allowed in its namespace
+// is the one applied.
+func (r *IPWhitelistConfigReconciler) ingressProviderSelector(ctx context.Context, config *beta1.IPWhitelistConfig, ing *knet.Ingress, name string) (*beta1.ProviderSelector, error) {
+	for _, rule := range config.Spec.Rules {
+		selector, err := metav1.LabelSelectorAsSelector(rule.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(ing.GetLabels())) {
+			continue
+		}
+		allowed, err := r.namespaceAllowed(ctx, rule.AllowedNamespaces, ing.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+		for i := range rule.ProviderSelector {
+			if rule.ProviderSelector[i].Name == name {
+				return &rule.ProviderSelector[i], nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// acknowledgeSiteShieldMap acknowledges the proposal of the map of the provider and returns the map as it is then
+func (r *IPWhitelistConfigReconciler) acknowledgeSiteShieldMap(ctx context.Context, provider beta1.Providers, id int) (siteshield.Map, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout(provider))
+	defer cancel()
+	httpClient, err := r.providerClient(ctx, provider)
+	if err != nil {
+		return siteshield.Map{}, err
+	}
+	akaClient, err := r.getsiteShieldClient(ctx, httpClient, provider.Akamai)
+	if err != nil {
+		return siteshield.Map{}, err
+	}
+	return akaClient.acknowledgeMap(ctx, id)
+}
+
+// updateSiteShieldStatus reports the pending proposals of the maps of the provider in the status of the config, and
+// sets the SiteShieldProposalsPending condition from all the pending proposals
+func (r *IPWhitelistConfigReconciler) updateSiteShieldStatus(ctx context.Context, config *beta1.IPWhitelistConfig, provider beta1.Providers, maps []siteshield.Map) error {
+	var proposals []beta1.SiteShieldProposal
+	for _, proposal := range config.Status.SiteShieldProposals {
+		if proposal.Provider != provider.Name {
+			proposals = append(proposals, proposal)
+		}
+	}
+	for _, siteMap := range maps {
+		if !proposalPending(siteMap) {
+			continue
+		}
+		proposal := beta1.SiteShieldProposal{
+			Provider: provider.Name,
+			MapID:    siteMap.ID,
+			// the status only keeps seconds
+			AcknowledgeRequiredBy: metav1.NewTime(siteMap.AcknowledgeRequiredBy.Truncate(time.Second)),
+		}
+		for _, cidr := range siteMap.ProposedCIDRs {
+			proposal.ProposedCIDRs = append(proposal.ProposedCIDRs, cidr.String())
+		}
+		proposals = append(proposals, proposal)
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].Provider != proposals[j].Provider {
+			return proposals[i].Provider < proposals[j].Provider
+		}
+		return proposals[i].MapID < proposals[j].MapID
+	})
+
+	// the times read back from the status are in the local time zone, which the semantic equality ignores
+	if !equality.Semantic.DeepEqual(proposals, config.Status.SiteShieldProposals) {
+		patch := client.MergeFrom(config.DeepCopy())
+		config.Status.SiteShieldProposals = proposals
+		if err := r.Status().Patch(ctx, config, patch); err != nil {
+			return err
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:    beta1.ConditionSiteShieldProposalsPending,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonNoSiteShieldProposals,
+		Message: "no site shield map has proposed cidrs to acknowledge",
+	}
+	if len(proposals) > 0 {
+		var pending []string
+		for _, proposal := range proposals {
+			if proposal.AcknowledgeRequiredBy.IsZero() {
+				pending = append(pending, fmt.Sprintf("%s (map %d)", proposal.Provider, proposal.MapID))
+				continue
+			}
+			pending = append(pending, fmt.Sprintf("%s (map %d, acknowledge by %s)", proposal.Provider, proposal.MapID, proposal.AcknowledgeRequiredBy.UTC().Format(time.RFC3339)))
+		}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonSiteShieldProposalsPending
+		condition.Message = "site shield maps with proposed cidrs to acknowledge: " + strings.Join(pending, ", ")
+	}
+	_, err := r.setConfigCondition(ctx, config, condition)
+	return err
+}
@@ -0,0 +1,235 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/corbaltcode/go-akamai/siteshield"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"inet.af/netaddr"
+	corev1 "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+var _ = Describe("Site Shield maps", func() {
+	ctx := context.Background()
+	deadline := time.Date(2023, 1, 31, 12, 0, 0, 0, time.UTC)
+	var acknowledged atomic.Bool
+	var acknowledgements atomic.Int32
+	var server *httptest.Server
+	var r *IPWhitelistConfigReconciler
+	var c client.Client
+	var config *beta1.IPWhitelistConfig
+	var provider beta1.Providers
+
+	siteMap := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"id": 1234, "acknowledged": %t, "acknowledgeRequiredBy": %d, "currentCidrs": ["23.48.168.0/22"], "proposedCidrs": ["23.48.172.0/22"]}`,
+			acknowledged.Load(), deadline.UnixMilli())
+	}
+
+	dualStackMap := `{"id": 5678, "acknowledged": true, "currentCidrs": ["23.48.168.0/22", "2600:1400::/24"], "proposedCidrs": []}`
+
+	BeforeEach(func() {
+		acknowledged.Store(false)
+		acknowledgements.Store(0)
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method == http.MethodGet && req.URL.Path == "/siteshield/v1/maps/1234":
+				siteMap(w)
+			case req.Method == http.MethodGet && req.URL.Path == "/siteshield/v1/maps/5678":
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(dualStackMap))
+			case req.Method == http.MethodGet && req.URL.Path == "/siteshield/v1/maps":
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"siteShieldMaps": [` + dualStackMap + `]}`))
+			case req.Method == http.MethodPost && req.URL.Path == "/siteshield/v1/maps/1234/acknowledge":
+				acknowledgements.Add(1)
+				acknowledged.Store(true)
+				siteMap(w)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		testScheme := runtime.NewScheme()
+		Expect(beta1.AddToScheme(testScheme)).To(Succeed())
+		Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+		Expect(knet.AddToScheme(testScheme)).To(Succeed())
+		ref := func(key string) *beta1.SecretKeySelector {
+			return &beta1.SecretKeySelector{Secret: corev1.SecretReference{Name: "akamai", Namespace: "default"}, Key: key}
+		}
+		mapID := intstr.FromInt(1234)
+		provider = beta1.Providers{
+			Name: "akamai",
+			Type: beta1.Akamai,
+			Akamai: beta1.AkamaiProvider{
+				MapId:        &mapID,
+				Host:         ref("host"),
+				ClientToken:  ref("client_token"),
+				ClientSecret: ref("client_secret"),
+				AccessToken:  ref("access_token"),
+			},
+			HTTPClient: &beta1.ProviderHTTPClient{CABundle: &beta1.CABundleSource{SecretRef: ref("ca.crt")}},
+		}
+		config = &beta1.IPWhitelistConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "ruleset"},
+			Spec: beta1.IPWhitelistConfigSpec{
+				WhitelistAnnotation: "ingress.kubernetes.io/whitelist-source-range",
+				Rules: []beta1.Rule{{
+					Name:             "akamai",
+					Selector:         &metav1.LabelSelector{MatchLabels: map[string]string{"ipwhitelist-type": "akamai"}},
+					ProviderSelector: []beta1.ProviderSelector{{Name: "akamai"}},
+				}},
+				Providers: []beta1.Providers{provider},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&beta1.IPWhitelistConfig{}).WithObjects(
+			config,
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "akamai", Namespace: "default"},
+				Data: map[string][]byte{
+					"host":          []byte(server.Listener.Addr().String()),
+					"client_token":  []byte("token"),
+					"client_secret": []byte("secret"),
+					"access_token":  []byte("access"),
+					"ca.crt":        pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+				},
+			},
+		).Build()
+		r = &IPWhitelistConfigReconciler{Client: c, Log: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	ingress := func(whitelist string) *knet.Ingress {
+		return &knet.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "web",
+				Namespace:   "default",
+				Labels:      map[string]string{"ipwhitelist-type": "akamai"},
+				Annotations: map[string]string{config.Spec.WhitelistAnnotation: whitelist},
+			},
+		}
+	}
+
+	It("should whitelist all the maps of the provider, each tagged with its id", func() {
+		provider.Akamai.MapIds = []intstr.IntOrString{intstr.FromString("5678")}
+		cidrs, err := r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{
+			netaddr.MustParseIPPrefix("23.48.172.0/22"),
+			netaddr.MustParseIPPrefix("23.48.168.0/22"),
+			netaddr.MustParseIPPrefix("2600:1400::/24"),
+		}))
+
+		By("filtering by map and ip family")
+		cidrs, err = r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, &beta1.ProviderFilter{Services: []string{"5678"}, IPFamily: beta1.IPFamilyIPv6})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("2600:1400::/24")}))
+	})
+	It("should whitelist all the maps visible to the credentials with allMaps", func() {
+		provider.Akamai.AllMaps = true
+		cidrs, err := r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(HaveLen(2))
+		Expect(providerSource(provider)).To(Equal("akamai site shield maps all"))
+
+		provider.Akamai.AllMaps, provider.Akamai.MapId = false, nil
+		_, err = r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).To(MatchError(ContainSubstring("mapId, mapIds or allMaps is required")))
+	})
+	It("should only report the proposal with Manual", func() {
+		_, err := r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(acknowledgements.Load()).To(BeZero())
+
+		Expect(config.Status.SiteShieldProposals).To(HaveLen(1))
+		proposal := config.Status.SiteShieldProposals[0]
+		Expect(proposal.Provider).To(Equal("akamai"))
+		Expect(proposal.MapID).To(Equal(1234))
+		Expect(proposal.ProposedCIDRs).To(Equal([]string{"23.48.172.0/22"}))
+		Expect(proposal.AcknowledgeRequiredBy.Time).To(BeTemporally("==", deadline))
+		Expect(meta.IsStatusConditionTrue(config.Status.Conditions, beta1.ConditionSiteShieldProposalsPending)).To(BeTrue())
+
+		By("clearing the proposal once it is acknowledged")
+		acknowledged.Store(true)
+		_, err = r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Status.SiteShieldProposals).To(BeEmpty())
+		Expect(meta.IsStatusConditionFalse(config.Status.Conditions, beta1.ConditionSiteShieldProposalsPending)).To(BeTrue())
+	})
+	It("should whitelist the current and the proposed cidrs with the Union transition", func() {
+		cidrs, err := r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("23.48.172.0/22")}))
+
+		provider.Akamai.Transition = beta1.SiteShieldTransitionUnion
+		cidrs, err = r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cidrs).To(Equal([]netaddr.IPPrefix{netaddr.MustParseIPPrefix("23.48.168.0/22"), netaddr.MustParseIPPrefix("23.48.172.0/22")}))
+
+		By("collapsing to the current cidrs once the map moved")
+		current := []netaddr.IPPrefix{netaddr.MustParseIPPrefix("23.48.172.0/22")}
+		Expect(akamaiCidrs(siteshield.Map{Acknowledged: true, CurrentCIDRs: current}, beta1.SiteShieldTransitionUnion)).To(Equal(current))
+	})
+	It("should acknowledge the proposal right away with Auto", func() {
+		provider.Akamai.Acknowledge = beta1.SiteShieldAcknowledgeAuto
+		_, err := r.providerCIDRs(ctx, config, &knet.Ingress{}, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(acknowledgements.Load()).To(Equal(int32(1)))
+		Expect(config.Status.SiteShieldProposals).To(BeEmpty())
+	})
+	It("should acknowledge the proposal with AfterApplied once every ingress using the provider has it", func() {
+		provider.Akamai.Acknowledge = beta1.SiteShieldAcknowledgeAfterApplied
+		ing := ingress("23.48.168.0/22")
+		Expect(c.Create(ctx, ing)).To(Succeed())
+		unrelated := ingress("10.0.0.0/8")
+		unrelated.Name, unrelated.Labels = "internal", nil
+		Expect(c.Create(ctx, unrelated)).To(Succeed())
+
+		_, err := r.providerCIDRs(ctx, config, ing, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(acknowledgements.Load()).To(BeZero())
+		Expect(config.Status.SiteShieldProposals).To(HaveLen(1))
+
+		ing.Annotations[config.Spec.WhitelistAnnotation] = "23.48.172.0/22"
+		Expect(c.Update(ctx, ing)).To(Succeed())
+		_, err = r.providerCIDRs(ctx, config, ing, provider, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(acknowledgements.Load()).To(Equal(int32(1)))
+		Expect(config.Status.SiteShieldProposals).To(BeEmpty())
+	})
+})
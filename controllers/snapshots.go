@@ -0,0 +1,265 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"inet.af/netaddr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// snapshotRefreshInterval is how often the fetchedAt of an unchanged snapshot is refreshed, so that not every reconcile
+// writes the snapshot while it still shows roughly when the cidrs were last seen
+const snapshotRefreshInterval = time.Hour
+
+// providerSource describes where the cidrs of the provider come from, a snapshot is only used for the same source
+func providerSource(provider beta1.Providers) string {
+	switch provider.Type {
+	case beta1.Cloudflare:
+		return provider.Cloudflare.JsonApi
+	case beta1.Github:
+		return provider.Github.JsonApi + "#" + strings.Join(provider.Github.Services, ",")
+	case beta1.Akamai:
+		var ids []string
+		for _, id := range siteShieldMapIDs(provider.Akamai) {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		switch {
+		case provider.Akamai.AllMaps:
+			return "akamai site shield maps all"
+		case len(ids) == 0:
+			return "akamai site shield map"
+		case len(ids) == 1:
+			return "akamai site shield map " + ids[0]
+		}
+		return "akamai site shield maps " + strings.Join(ids, ",")
+	case beta1.AWS:
+		return strings.Join([]string{
+			provider.AWS.JsonApi,
+			strings.Join(provider.AWS.Services, ","),
+			strings.Join(provider.AWS.Regions, ","),
+			strings.Join(provider.AWS.NetworkBorderGroups, ","),
+		}, "#")
+	case beta1.GCP:
+		return strings.Join([]string{
+			provider.GCP.JsonApi,
+			strings.Join(provider.GCP.Scopes, ","),
+			strings.Join(provider.GCP.Services, ","),
+		}, "#")
+	case beta1.Azure:
+		if provider.Azure == nil {
+			return ""
+		}
+		return strings.Join([]string{
+			provider.Azure.JsonApi,
+			strings.Join(provider.Azure.ServiceTags, ","),
+			strings.Join(provider.Azure.Regions, ","),
+		}, "#")
+	case beta1.Fastly:
+		return provider.Fastly.JsonApi
+	}
+	return ""
+}
+
+// snapshotHash returns the sha256 of the sorted cidrs, one per line
+func snapshotHash(cidrs []string) string {
+	sorted := append([]string(nil), cidrs...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotSpecHash returns the sha256 of the sorted cidrs of the snapshot, one per line, followed by a line for every
+// service and region with their sorted cidrs, so that the tags the rules filter by are checked too. Without any tags it
+// is the snapshotHash of the cidrs.
+func snapshotSpecHash(cidrs []string, services, regions map[string][]string) string {
+	lines := append([]string(nil), cidrs...)
+	sort.Strings(lines)
+	lines = append(lines, tagHashLines("service", services)...)
+	lines = append(lines, tagHashLines("region", regions)...)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// tagHashLines returns a line for every tag, sorted by name, with its sorted cidrs
+func tagHashLines(kind string, tags map[string][]string) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		cidrs := append([]string(nil), tags[name]...)
+		sort.Strings(cidrs)
+		lines = append(lines, fmt.Sprintf("%s %s %s", kind, name, strings.Join(cidrs, ",")))
+	}
+	return lines
+}
+
+// saveSnapshot writes the fetched cidrs to the ProviderSnapshot of the provider. An unchanged snapshot is only written
+// again after the snapshotRefreshInterval.
+func (r *IPWhitelistConfigReconciler) saveSnapshot(ctx context.Context, provider beta1.Providers, cidrs []netaddr.IPPrefix, tags cidrTags, now time.Time) error {
+	spec := beta1.ProviderSnapshotSpec{
+		Type:      provider.Type,
+		Source:    providerSource(provider),
+		FetchedAt: metav1.NewTime(now),
+	}
+	positions := make(map[netaddr.IPPrefix]int32, len(cidrs))
+	for i, cidr := range cidrs {
+		positions[cidr] = int32(i)
+		spec.CIDRs = append(spec.CIDRs, cidr.String())
+	}
+	var err error
+	if spec.Services, err = tagIndices(tags.services, positions); err != nil {
+		return err
+	}
+	if spec.Regions, err = tagIndices(tags.regions, positions); err != nil {
+		return err
+	}
+	spec.Hash = snapshotSpecHash(spec.CIDRs, tagStrings(tags.services), tagStrings(tags.regions))
+
+	snapshot := &beta1.ProviderSnapshot{}
+	err = r.Get(ctx, client.ObjectKey{Name: provider.Name}, snapshot)
+	if apierrors.IsNotFound(err) {
+		snapshot = &beta1.ProviderSnapshot{ObjectMeta: metav1.ObjectMeta{Name: provider.Name}, Spec: spec}
+		return r.Create(ctx, snapshot)
+	}
+	if err != nil {
+		return err
+	}
+	unchanged := snapshot.Spec.Hash == spec.Hash && snapshot.Spec.Source == spec.Source
+	if unchanged && now.Sub(snapshot.Spec.FetchedAt.Time) < snapshotRefreshInterval {
+		return nil
+	}
+	snapshot.Spec = spec
+	return r.Update(ctx, snapshot)
+}
+
+// loadSnapshot returns the ProviderSnapshot of the provider, its cidrs and their tags, or nil if there is none for the
+// same source
+func (r *IPWhitelistConfigReconciler) loadSnapshot(ctx context.Context, provider beta1.Providers) (*beta1.ProviderSnapshot, []netaddr.IPPrefix, cidrTags, error) {
+	snapshot := &beta1.ProviderSnapshot{}
+	if err := r.Get(ctx, client.ObjectKey{Name: provider.Name}, snapshot); err != nil {
+		return nil, nil, cidrTags{}, client.IgnoreNotFound(err)
+	}
+	if snapshot.Spec.Type != provider.Type || snapshot.Spec.Source != providerSource(provider) {
+		return nil, nil, cidrTags{}, nil
+	}
+	services, err := snapshotTagCIDRs(snapshot.Name, "service", snapshot.Spec.CIDRs, snapshot.Spec.Services)
+	if err != nil {
+		return nil, nil, cidrTags{}, err
+	}
+	regions, err := snapshotTagCIDRs(snapshot.Name, "region", snapshot.Spec.CIDRs, snapshot.Spec.Regions)
+	if err != nil {
+		return nil, nil, cidrTags{}, err
+	}
+	if snapshot.Spec.Hash != "" && snapshot.Spec.Hash != snapshotSpecHash(snapshot.Spec.CIDRs, services, regions) {
+		return nil, nil, cidrTags{}, fmt.Errorf("providerSnapshot %s does not match its hash", snapshot.Name)
+	}
+
+	cidrs, err := parseSnapshotCIDRs(snapshot.Name, snapshot.Spec.CIDRs)
+	if err != nil {
+		return nil, nil, cidrTags{}, err
+	}
+	// the cidrs of the tags are parsed with the cidrs of the snapshot
+	var tags cidrTags
+	for service, indices := range snapshot.Spec.Services {
+		for _, i := range indices {
+			tags.addService(service, cidrs[i])
+		}
+	}
+	for region, indices := range snapshot.Spec.Regions {
+		for _, i := range indices {
+			tags.addRegion(region, cidrs[i])
+		}
+	}
+	return snapshot, cidrs, tags, nil
+}
+
+// snapshotTagCIDRs returns the cidrs of every tag of the snapshot, which are stored as indices into its cidrs
+func snapshotTagCIDRs(name, kind string, cidrs []string, index map[string][]int32) (map[string][]string, error) {
+	if len(index) == 0 {
+		return nil, nil
+	}
+	strs := make(map[string][]string, len(index))
+	for tag, indices := range index {
+		for _, i := range indices {
+			if i < 0 || int(i) >= len(cidrs) {
+				return nil, fmt.Errorf("providerSnapshot %s has an invalid cidr index %d for the %s %s", name, i, kind, tag)
+			}
+			strs[tag] = append(strs[tag], cidrs[i])
+		}
+	}
+	return strs, nil
+}
+
+func parseSnapshotCIDRs(name string, cidrs []string) ([]netaddr.IPPrefix, error) {
+	prefixes := make([]netaddr.IPPrefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netaddr.ParseIPPrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("providerSnapshot %s has an invalid cidr %s: %w", name, cidr, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// tagStrings returns the cidrs of every tag as strings, nil if there are no tags
+func tagStrings(index map[string][]netaddr.IPPrefix) map[string][]string {
+	if len(index) == 0 {
+		return nil
+	}
+	strs := make(map[string][]string, len(index))
+	for name, cidrs := range index {
+		strs[name] = prefixStrings(cidrs)
+	}
+	return strs
+}
+
+// tagIndices returns the indices into the cidrs of the snapshot of the cidrs of every tag, nil if there are no tags. A
+// tag only keeps the indices, an unfiltered document can have its cidrs in several tags and the cidrs as strings in
+// every one of them would make the snapshot too big to store.
+func tagIndices(index map[string][]netaddr.IPPrefix, positions map[netaddr.IPPrefix]int32) (map[string][]int32, error) {
+	if len(index) == 0 {
+		return nil, nil
+	}
+	indices := make(map[string][]int32, len(index))
+	for name, cidrs := range index {
+		for _, cidr := range cidrs {
+			i, ok := positions[cidr]
+			if !ok {
+				return nil, fmt.Errorf("the cidr %s of the tag %s is not one of the cidrs of the provider", cidr, name)
+			}
+			indices[name] = append(indices[name], i)
+		}
+	}
+	return indices, nil
+}
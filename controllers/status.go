@@ -0,0 +1,88 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
+)
+
+// setConfigCondition sets the condition on the IPWhitelistConfig status, will return true if the condition changed.
+// Every ingress reconcile computes the same conditions, so the status is only patched when something changed.
+func (r *IPWhitelistConfigReconciler) setConfigCondition(ctx context.Context, config *beta1.IPWhitelistConfig, condition metav1.Condition) (bool, error) {
+	existing := meta.FindStatusCondition(config.Status.Conditions, condition.Type)
+	if existing != nil &&
+		existing.Status == condition.Status &&
+		existing.Reason == condition.Reason &&
+		existing.Message == condition.Message &&
+		existing.ObservedGeneration == config.Generation {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(config.DeepCopy())
+	condition.ObservedGeneration = config.Generation
+	meta.SetStatusCondition(&config.Status.Conditions, condition)
+
+	return true, r.Status().Patch(ctx, config, patch)
+}
+
+// raisedEvents keeps the state each warning event was last raised for, so that a warning lasting over many reconciles
+// is only raised again when its state changes. The zero value is ready to use.
+type raisedEvents struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+// changed records the state of the key, will return true if it differs from the state recorded before
+func (e *raisedEvents) changed(key, state string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.states == nil {
+		e.states = make(map[string]string)
+	}
+	if previous, ok := e.states[key]; ok && previous == state {
+		return false
+	}
+	e.states[key] = state
+	return true
+}
+
+// forget removes the state of the key, so that the warning is raised again once it comes back
+func (e *raisedEvents) forget(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.states, key)
+}
+
+// forgetOthers removes the state of every key with the prefix that is not kept, for the warnings whose cause can go
+// away without being seen again, like a cidr removed from its group
+func (e *raisedEvents) forgetOthers(prefix string, keep map[string]bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key := range e.states {
+		if strings.HasPrefix(key, prefix) && !keep[key] {
+			delete(e.states, key)
+		}
+	}
+}
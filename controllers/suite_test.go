@@ -93,11 +93,13 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&IPWhitelistConfigReconciler{
-		Client:            K8sManager.GetClient(),
-		Scheme:            K8sManager.GetScheme(),
-		IPWhitelistConfig: RuleSetName,
-		RequeueInterval:   5 * time.Second,
-		Log:               ctrl.Log.WithName("controllers test").WithName("IPWhitelistConfig"),
+		Client:              K8sManager.GetClient(),
+		Scheme:              K8sManager.GetScheme(),
+		IPWhitelistConfig:   RuleSetName,
+		RequeueInterval:     5 * time.Second,
+		Log:                 ctrl.Log.WithName("controllers test").WithName("IPWhitelistConfig"),
+		Recorder:            K8sManager.GetEventRecorderFor("ingress-whitelister"),
+		ExpiryWarningWindow: 7 * 24 * time.Hour,
 	}).SetupWithManager(K8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
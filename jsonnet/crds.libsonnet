@@ -40,24 +40,115 @@
                   properties: {
                     ipGroups: {
                       items: {
-                        description: 'IPGroup is a group of IPs with a set expiration time',
+                        description: 'InlineIPGroup is an IPGroup defined inside the IPWhitelistConfig',
                         properties: {
                           cidrs: {
+                            description: 'CIDRS are either plain strings like "10.0.0.0/8" or objects with their own expiry',
                             items: {
-                              type: 'string',
+                              description: 'CIDR is a single entry of an IPGroup. For compatibility, it can also be given as a plain string, which is the same as\nan entry with only the cidr set. The schema accepts both forms, so the entries are checked when decoded instead: an\nentry with an unknown key or without its cidr is refused, rather than silently losing the misspelled field.',
+                              properties: {
+                                cidr: {
+                                  type: 'string',
+                                },
+                                description: {
+                                  description: 'Description of who or what the CIDR belongs to',
+                                  type: 'string',
+                                },
+                                expires: {
+                                  description: 'Expires is the time from which this CIDR is no longer whitelisted, the expiry of the group still applies',
+                                  format: 'date-time',
+                                  type: 'string',
+                                },
+                                ticket: {
+                                  description: 'Ticket is the change request or issue the CIDR was requested in',
+                                  type: 'string',
+                                },
+                              },
+                              required: [
+                                'cidr',
+                              ],
+                              'x-kubernetes-preserve-unknown-fields': true,
                             },
                             type: 'array',
                           },
                           expires: {
+                            description: 'Expires is the time from which the group is no longer whitelisted, the group never expires if not set',
                             format: 'date-time',
                             type: 'string',
                           },
+                          includeGroups: {
+                            description: 'IncludeGroups are the names of other groups whose cidrs are whitelisted along with this group.\nEvery included group still has its own expiry, notBefore and schedule applied.',
+                            items: {
+                              type: 'string',
+                            },
+                            type: 'array',
+                          },
                           name: {
                             type: 'string',
                           },
+                          notBefore: {
+                            description: 'NotBefore is the time from which the group is whitelisted, the group is active right away if not set',
+                            format: 'date-time',
+                            type: 'string',
+                          },
+                          schedule: {
+                            description: 'Schedule limits the group to recurring time windows, the group is whitelisted all the time if not set',
+                            properties: {
+                              timeZone: {
+                                default: 'UTC',
+                                description: 'TimeZone is the IANA name of the time zone the windows are in, like "Europe/Berlin"',
+                                type: 'string',
+                              },
+                              windows: {
+                                description: 'Windows during which the group is whitelisted, the group is whitelisted while any of them is open',
+                                items: {
+                                  description: 'TimeWindow is a time range repeating on the given days of the week',
+                                  properties: {
+                                    days: {
+                                      description: 'Days of the week on which the window opens, every day if not set',
+                                      items: {
+                                        description: 'Weekday is a day of the week',
+                                        enum: [
+                                          'Monday',
+                                          'Tuesday',
+                                          'Wednesday',
+                                          'Thursday',
+                                          'Friday',
+                                          'Saturday',
+                                          'Sunday',
+                                        ],
+                                        type: 'string',
+                                      },
+                                      type: 'array',
+                                    },
+                                    end: {
+                                      description: 'End is the time of the day as HH:MM at which the window closes.\nA window ending at or before its start closes on the next day, like 22:00 to 06:00.',
+                                      pattern: '^([01][0-9]|2[0-3]):[0-5][0-9]$',
+                                      type: 'string',
+                                    },
+                                    start: {
+                                      description: 'Start is the time of the day as HH:MM at which the window opens',
+                                      pattern: '^([01][0-9]|2[0-3]):[0-5][0-9]$',
+                                      type: 'string',
+                                    },
+                                  },
+                                  required: [
+                                    'end',
+                                    'start',
+                                  ],
+                                  type: 'object',
+                                },
+                                minItems: 1,
+                                type: 'array',
+                              },
+                            },
+                            required: [
+                              'windows',
+                            ],
+                            type: 'object',
+                          },
                         },
                         required: [
-                          'expires',
                           'name',
                         ],
                         type: 'object',
@@ -68,6 +159,33 @@
                       ],
                       'x-kubernetes-list-type': 'map',
                     },
+                    policy: {
+                      description: 'Policy bounds the CIDRs of all the ipGroups used by the rules, a rule using a group violating it is not applied.\nThe providers are not checked.',
+                      properties: {
+                        forbiddenCIDRs: {
+                          description: 'ForbiddenCIDRs may not be whitelisted, neither themselves, nor as part of a larger CIDR, nor in part or split up\ninto smaller CIDRs, like 169.254.0.0/16. Forbidding 0.0.0.0/0 forbids all of IPv4, the size of the CIDRs is\nbounded by the minimum prefix lengths instead.',
+                          items: {
+                            type: 'string',
+                          },
+                          type: 'array',
+                        },
+                        minPrefixLengthIPv4: {
+                          description: 'MinPrefixLengthIPv4 is the smallest prefix length, so the largest IPv4 CIDR, that may be whitelisted',
+                          format: 'int32',
+                          maximum: 32,
+                          minimum: 0,
+                          type: 'integer',
+                        },
+                        minPrefixLengthIPv6: {
+                          description: 'MinPrefixLengthIPv6 is the smallest prefix length, so the largest IPv6 CIDR, that may be whitelisted',
+                          format: 'int32',
+                          maximum: 128,
+                          minimum: 0,
+                          type: 'integer',
+                        },
+                      },
+                      type: 'object',
+                    },
                     providers: {
                       items: {
                         properties: {
@@ -100,6 +218,20 @@
                                 ],
                                 type: 'object',
                               },
+                              acknowledge: {
+                                default: 'Manual',
+                                description: 'Acknowledge decides who acknowledges the proposed cidrs of the map',
+                                enum: [
+                                  'Manual',
+                                  'Auto',
+                                  'AfterApplied',
+                                ],
+                                type: 'string',
+                              },
+                              allMaps: {
+                                description: 'AllMaps whitelists all the Site Shield maps visible to the credentials, mapId and mapIds are ignored',
+                                type: 'boolean',
+                              },
                               clientSecretSecretRef: {
                                 properties: {
                                   key: {
@@ -154,6 +286,47 @@
                                 ],
                                 type: 'object',
                               },
+                              credentialsFromEnv: {
+                                description: 'CredentialsFromEnv reads the credentials from the AKAMAI_HOST, AKAMAI_CLIENT_TOKEN, AKAMAI_CLIENT_SECRET and\nAKAMAI_ACCESS_TOKEN environment variables of the operator, AKAMAI_<SECTION>_HOST and so on for another section\nthan default',
+                                type: 'boolean',
+                              },
+                              edgercPath: {
+                                description: 'EdgercPath is the path of an .edgerc file with the credentials mounted into the pod of the operator',
+                                type: 'string',
+                              },
+                              edgercSecretRef: {
+                                description: 'EdgercSecretRef is a Secret key holding an .edgerc file with the credentials, used instead of the four SecretRefs',
+                                properties: {
+                                  key: {
+                                    type: 'string',
+                                  },
+                                  secret: {
+                                    description: 'SecretReference represents a Secret Reference. It has enough information to retrieve secret\nin any namespace',
+                                    properties: {
+                                      name: {
+                                        description: 'name is unique within a namespace to reference a secret resource.',
+                                        type: 'string',
+                                      },
+                                      namespace: {
+                                        description: 'namespace defines the space within which the secret name must be unique.',
+                                        type: 'string',
+                                      },
+                                    },
+                                    type: 'object',
+                                    'x-kubernetes-map-type': 'atomic',
+                                  },
+                                },
+                                required: [
+                                  'key',
+                                  'secret',
+                                ],
+                                type: 'object',
+                              },
+                              edgercSection: {
+                                default: 'default',
+                                description: 'EdgercSection is the section of the .edgerc file with the credentials, also the section of the credentials read\nfrom the environment',
+                                type: 'string',
+                              },
                               mapId: {
                                 anyOf: [
                                   {
@@ -165,6 +338,21 @@
                                 ],
                                 'x-kubernetes-int-or-string': true,
                               },
+                              mapIds: {
+                                description: 'MapIds are more Site Shield maps to whitelist along with mapId',
+                                items: {
+                                  anyOf: [
+                                    {
+                                      type: 'integer',
+                                    },
+                                    {
+                                      type: 'string',
+                                    },
+                                  ],
+                                  'x-kubernetes-int-or-string': true,
+                                },
+                                type: 'array',
+                              },
                               serviceConsumerDomainRef: {
                                 properties: {
                                   key: {
@@ -192,7 +380,77 @@
                                 ],
                                 type: 'object',
                               },
+                              transition: {
+                                default: 'Proposed',
+                                description: 'Transition decides which cidrs of the map are whitelisted while it has proposed cidrs',
+                                enum: [
+                                  'Proposed',
+                                  'Union',
+                                ],
+                                type: 'string',
+                              },
+                            },
+                            type: 'object',
+                          },
+                          aws: {
+                            description: 'AWSProvider is a provider for the ip-ranges.json of AWS. A prefix is whitelisted if it matches all of services,\nregions and networkBorderGroups.',
+                            properties: {
+                              jsonApi: {
+                                default: 'https://ip-ranges.amazonaws.com/ip-ranges.json',
+                                type: 'string',
+                              },
+                              networkBorderGroups: {
+                                description: 'NetworkBorderGroups of the prefixes like us-west-2 or us-west-2-lax-1, all the network border groups if not set',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              regions: {
+                                description: 'Regions of the prefixes like eu-west-1 or GLOBAL, all the regions if not set',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              services: {
+                                description: 'Services of the prefixes like CLOUDFRONT_ORIGIN_FACING or ROUTE53_HEALTHCHECKS, all the services if not set',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                            },
+                            type: 'object',
+                          },
+                          azure: {
+                            description: 'AzureProvider is a provider for the Service Tags JSON of Azure. A prefix is whitelisted if its service tag matches\nboth serviceTags and regions.',
+                            properties: {
+                              jsonApi: {
+                                description: 'JsonApi is the url of the Service Tags JSON, like the ServiceTags_Public_<date>.json published weekly by Microsoft',
+                                minLength: 1,
+                                type: 'string',
+                              },
+                              regions: {
+                                description: 'Regions of the service tags like westeurope, all the regions if not set. Global service tags have no region.',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              serviceTags: {
+                                description: 'ServiceTags are the names of the service tags like AzureFrontDoor.Backend. They have to be set, all the service\ntags together are too many cidrs to store in the ProviderSnapshot.',
+                                items: {
+                                  type: 'string',
+                                },
+                                minItems: 1,
+                                type: 'array',
+                              },
                             },
+                            required: [
+                              'jsonApi',
+                              'serviceTags',
+                            ],
                             type: 'object',
                           },
                           cloudflare: {
@@ -208,6 +466,16 @@
                             ],
                             type: 'object',
                           },
+                          failurePolicy: {
+                            default: 'Fail',
+                            description: 'FailurePolicy decides what happens to the rules using the provider when fetching its cidrs fails',
+                            enum: [
+                              'Fail',
+                              'UseLastKnownGood',
+                              'Skip',
+                            ],
+                            type: 'string',
+                          },
                           fastly: {
                             properties: {
                               jsonApi: {
@@ -219,6 +487,31 @@
                             ],
                             type: 'object',
                           },
+                          gcp: {
+                            description: 'GCPProvider is a provider for the ip ranges published by Google, either cloud.json with the ranges of Google Cloud\nor goog.json with the ranges of the Google services. A prefix is whitelisted if it matches both scopes and services.',
+                            properties: {
+                              jsonApi: {
+                                default: 'https://www.gstatic.com/ipranges/cloud.json',
+                                description: 'JsonApi is https://www.gstatic.com/ipranges/cloud.json or https://www.gstatic.com/ipranges/goog.json',
+                                type: 'string',
+                              },
+                              scopes: {
+                                description: 'Scopes of the prefixes of cloud.json like us-central1 or europe-west1, all the scopes if not set',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              services: {
+                                description: 'Services of the prefixes of cloud.json like "Google Cloud", all the services if not set',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                            },
+                            type: 'object',
+                          },
                           github: {
                             description: 'GithubProvider is a provider for the GitHub meta API',
                             properties: {
@@ -226,31 +519,201 @@
                                 default: '2022-11-28',
                                 type: 'string',
                               },
+                              flavor: {
+                                default: 'dotcom',
+                                description: 'Flavor is the kind of GitHub serving the jsonApi. The jsonApi of GitHub Enterprise Server has to be set, like\nhttps://github.example.com/api/v3/meta',
+                                enum: [
+                                  'dotcom',
+                                  'enterprise',
+                                ],
+                                type: 'string',
+                              },
                               jsonApi: {
                                 default: 'https://api.github.com/meta',
                                 type: 'string',
                               },
                               services: {
-                                description: 'Services are names of sections with IP addresses in the api.github.com/meta like "hooks", "web", "api", "actions" etc',
+                                description: 'Services are names of sections with IP addresses in the api.github.com/meta like "hooks", "web", "api", "actions"\netc, all the services if not set',
                                 items: {
                                   type: 'string',
                                 },
                                 type: 'array',
                               },
+                              tokenSecretRef: {
+                                description: 'TokenSecretRef is a GitHub token sent with the requests, for the higher rate limit of authenticated requests',
+                                properties: {
+                                  key: {
+                                    type: 'string',
+                                  },
+                                  secret: {
+                                    description: 'SecretReference represents a Secret Reference. It has enough information to retrieve secret\nin any namespace',
+                                    properties: {
+                                      name: {
+                                        description: 'name is unique within a namespace to reference a secret resource.',
+                                        type: 'string',
+                                      },
+                                      namespace: {
+                                        description: 'namespace defines the space within which the secret name must be unique.',
+                                        type: 'string',
+                                      },
+                                    },
+                                    type: 'object',
+                                    'x-kubernetes-map-type': 'atomic',
+                                  },
+                                },
+                                required: [
+                                  'key',
+                                  'secret',
+                                ],
+                                type: 'object',
+                              },
+                            },
+                            type: 'object',
+                          },
+                          httpClient: {
+                            description: 'HTTPClient configures the http client used to fetch the provider',
+                            properties: {
+                              caBundle: {
+                                description: 'CABundle is the PEM encoded CAs trusted for the provider, in addition to the ones of the system',
+                                properties: {
+                                  configMapRef: {
+                                    properties: {
+                                      configMap: {
+                                        description: 'ConfigMapReference is the name and namespace of a ConfigMap, like a SecretReference',
+                                        properties: {
+                                          name: {
+                                            type: 'string',
+                                          },
+                                          namespace: {
+                                            type: 'string',
+                                          },
+                                        },
+                                        required: [
+                                          'name',
+                                          'namespace',
+                                        ],
+                                        type: 'object',
+                                      },
+                                      key: {
+                                        type: 'string',
+                                      },
+                                    },
+                                    required: [
+                                      'configMap',
+                                      'key',
+                                    ],
+                                    type: 'object',
+                                  },
+                                  secretRef: {
+                                    properties: {
+                                      key: {
+                                        type: 'string',
+                                      },
+                                      secret: {
+                                        description: 'SecretReference represents a Secret Reference. It has enough information to retrieve secret\nin any namespace',
+                                        properties: {
+                                          name: {
+                                            description: 'name is unique within a namespace to reference a secret resource.',
+                                            type: 'string',
+                                          },
+                                          namespace: {
+                                            description: 'namespace defines the space within which the secret name must be unique.',
+                                            type: 'string',
+                                          },
+                                        },
+                                        type: 'object',
+                                        'x-kubernetes-map-type': 'atomic',
+                                      },
+                                    },
+                                    required: [
+                                      'key',
+                                      'secret',
+                                    ],
+                                    type: 'object',
+                                  },
+                                },
+                                type: 'object',
+                              },
+                              clientCertificateSecretRef: {
+                                description: 'ClientCertificate is a kubernetes.io/tls Secret with the client certificate presented to the provider',
+                                properties: {
+                                  name: {
+                                    description: 'name is unique within a namespace to reference a secret resource.',
+                                    type: 'string',
+                                  },
+                                  namespace: {
+                                    description: 'namespace defines the space within which the secret name must be unique.',
+                                    type: 'string',
+                                  },
+                                },
+                                type: 'object',
+                                'x-kubernetes-map-type': 'atomic',
+                              },
+                              proxyURL: {
+                                description: 'ProxyURL is the proxy the provider is reached through, the HTTPS_PROXY and NO_PROXY of the operator are used if\nnot set',
+                                type: 'string',
+                              },
+                              retry: {
+                                description: 'Retry retries failed requests to the provider, requests are not retried if not set',
+                                properties: {
+                                  backoff: {
+                                    default: '1s',
+                                    description: 'Backoff is the wait before the first retry, doubled for every following one',
+                                    type: 'string',
+                                  },
+                                  maxRetries: {
+                                    default: 3,
+                                    description: 'MaxRetries is how many times a request is retried',
+                                    format: 'int32',
+                                    maximum: 10,
+                                    minimum: 0,
+                                    type: 'integer',
+                                  },
+                                },
+                                type: 'object',
+                              },
+                              timeout: {
+                                default: '30s',
+                                description: 'Timeout of a fetch of the provider, retries included',
+                                type: 'string',
+                              },
                             },
-                            required: [
-                              'services',
-                            ],
                             type: 'object',
                           },
+                          maxShrinkPercent: {
+                            description: 'MaxShrinkPercent is how much smaller, in percent of the number of cidrs, a fetch may be than the last known good\none. A fetch shrinking more is held back until it is acknowledged, no limit if not set.',
+                            format: 'int32',
+                            maximum: 100,
+                            minimum: 0,
+                            type: 'integer',
+                          },
+                          minEntries: {
+                            default: 1,
+                            description: 'MinEntries is the fewest cidrs a fetch may return. A fetch with fewer is held back until it is acknowledged.',
+                            format: 'int32',
+                            minimum: 0,
+                            type: 'integer',
+                          },
+                          mode: {
+                            default: 'Fetch',
+                            description: 'Mode decides where the cidrs of the provider come from',
+                            enum: [
+                              'Fetch',
+                              'SnapshotOnly',
+                            ],
+                            type: 'string',
+                          },
                           name: {
                             type: 'string',
                           },
                           type: {
                             enum: [
                               'akamai',
+                              'aws',
+                              'azure',
                               'cloudflare',
                               'fastly',
+                              'gcp',
                               'github',
                             ],
                             type: 'string',
@@ -272,18 +735,229 @@
                       items: {
                         description: 'Rule is mapping of an IPGroup to a set of labels',
                         properties: {
-                          ipGroupSelector: {
-                            items: {
-                              type: 'string',
-                            },
-                            type: 'array',
-                          },
-                          name: {
-                            type: 'string',
-                          },
-                          providerSelector: {
-                            items: {
-                              properties: {
+                          allowedNamespaces: {
+                            description: 'AllowedNamespaces restricts the rule to the ingresses in these namespaces, ingresses in any namespace may use it if\nnot set. A matching ingress in any other namespace is refused the rule, with a Warning event.',
+                            properties: {
+                              names: {
+                                description: 'Names of the allowed namespaces',
+                                items: {
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              selector: {
+                                description: 'Selector selects the allowed namespaces by their labels',
+                                properties: {
+                                  matchExpressions: {
+                                    description: 'matchExpressions is a list of label selector requirements. The requirements are ANDed.',
+                                    items: {
+                                      description: 'A label selector requirement is a selector that contains values, a key, and an operator that\nrelates the key and values.',
+                                      properties: {
+                                        key: {
+                                          description: 'key is the label key that the selector applies to.',
+                                          type: 'string',
+                                        },
+                                        operator: {
+                                          description: "operator represents a key's relationship to a set of values.\nValid operators are In, NotIn, Exists and DoesNotExist.",
+                                          type: 'string',
+                                        },
+                                        values: {
+                                          description: 'values is an array of string values. If the operator is In or NotIn,\nthe values array must be non-empty. If the operator is Exists or DoesNotExist,\nthe values array must be empty. This array is replaced during a strategic\nmerge patch.',
+                                          items: {
+                                            type: 'string',
+                                          },
+                                          type: 'array',
+                                        },
+                                      },
+                                      required: [
+                                        'key',
+                                        'operator',
+                                      ],
+                                      type: 'object',
+                                    },
+                                    type: 'array',
+                                  },
+                                  matchLabels: {
+                                    additionalProperties: {
+                                      type: 'string',
+                                    },
+                                    description: 'matchLabels is a map of {key,value} pairs. A single {key,value} in the matchLabels\nmap is equivalent to an element of matchExpressions, whose key field is "key", the\noperator is "In", and the values array contains only "value". The requirements are ANDed.',
+                                    type: 'object',
+                                  },
+                                },
+                                type: 'object',
+                                'x-kubernetes-map-type': 'atomic',
+                              },
+                            },
+                            type: 'object',
+                          },
+                          ipGroupLabelSelector: {
+                            description: 'IPGroupLabelSelector selects IPGroup objects by their labels, in addition to the ones named in ipGroupSelector',
+                            properties: {
+                              matchExpressions: {
+                                description: 'matchExpressions is a list of label selector requirements. The requirements are ANDed.',
+                                items: {
+                                  description: 'A label selector requirement is a selector that contains values, a key, and an operator that\nrelates the key and values.',
+                                  properties: {
+                                    key: {
+                                      description: 'key is the label key that the selector applies to.',
+                                      type: 'string',
+                                    },
+                                    operator: {
+                                      description: "operator represents a key's relationship to a set of values.\nValid operators are In, NotIn, Exists and DoesNotExist.",
+                                      type: 'string',
+                                    },
+                                    values: {
+                                      description: 'values is an array of string values. If the operator is In or NotIn,\nthe values array must be non-empty. If the operator is Exists or DoesNotExist,\nthe values array must be empty. This array is replaced during a strategic\nmerge patch.',
+                                      items: {
+                                        type: 'string',
+                                      },
+                                      type: 'array',
+                                    },
+                                  },
+                                  required: [
+                                    'key',
+                                    'operator',
+                                  ],
+                                  type: 'object',
+                                },
+                                type: 'array',
+                              },
+                              matchLabels: {
+                                additionalProperties: {
+                                  type: 'string',
+                                },
+                                description: 'matchLabels is a map of {key,value} pairs. A single {key,value} in the matchLabels\nmap is equivalent to an element of matchExpressions, whose key field is "key", the\noperator is "In", and the values array contains only "value". The requirements are ANDed.',
+                                type: 'object',
+                              },
+                            },
+                            type: 'object',
+                            'x-kubernetes-map-type': 'atomic',
+                          },
+                          ipGroupSelector: {
+                            description: 'IPGroupSelector are the names of the groups to whitelist, either defined in ipGroups or as IPGroup objects',
+                            items: {
+                              type: 'string',
+                            },
+                            type: 'array',
+                          },
+                          maxAddresses: {
+                            description: 'MaxAddresses is the most addresses the ipGroups of the rule may whitelist in total, the rule is not applied if\nthey whitelist more. The providers are not counted.',
+                            format: 'int64',
+                            minimum: 1,
+                            type: 'integer',
+                          },
+                          name: {
+                            type: 'string',
+                          },
+                          namespaceIPGroups: {
+                            description: 'NamespaceIPGroups opts the rule in to the NamespaceIPGroups in the namespace of the ingress, they are not\nwhitelisted if not set',
+                            properties: {
+                              policy: {
+                                description: 'Policy bounds what the NamespaceIPGroups may contain, CIDRs violating it are not whitelisted',
+                                properties: {
+                                  forbiddenCIDRs: {
+                                    description: 'ForbiddenCIDRs may not be whitelisted, neither themselves, nor as part of a larger CIDR, nor in part or split up\ninto smaller CIDRs, like 169.254.0.0/16. Forbidding 0.0.0.0/0 forbids all of IPv4, the size of the CIDRs is\nbounded by the minimum prefix lengths instead.',
+                                    items: {
+                                      type: 'string',
+                                    },
+                                    type: 'array',
+                                  },
+                                  minPrefixLengthIPv4: {
+                                    description: 'MinPrefixLengthIPv4 is the smallest prefix length, so the largest IPv4 CIDR, that may be whitelisted',
+                                    format: 'int32',
+                                    maximum: 32,
+                                    minimum: 0,
+                                    type: 'integer',
+                                  },
+                                  minPrefixLengthIPv6: {
+                                    description: 'MinPrefixLengthIPv6 is the smallest prefix length, so the largest IPv6 CIDR, that may be whitelisted',
+                                    format: 'int32',
+                                    maximum: 128,
+                                    minimum: 0,
+                                    type: 'integer',
+                                  },
+                                },
+                                type: 'object',
+                              },
+                              selector: {
+                                description: 'Selector selects the NamespaceIPGroups by their labels, all of them in the namespace if not set',
+                                properties: {
+                                  matchExpressions: {
+                                    description: 'matchExpressions is a list of label selector requirements. The requirements are ANDed.',
+                                    items: {
+                                      description: 'A label selector requirement is a selector that contains values, a key, and an operator that\nrelates the key and values.',
+                                      properties: {
+                                        key: {
+                                          description: 'key is the label key that the selector applies to.',
+                                          type: 'string',
+                                        },
+                                        operator: {
+                                          description: "operator represents a key's relationship to a set of values.\nValid operators are In, NotIn, Exists and DoesNotExist.",
+                                          type: 'string',
+                                        },
+                                        values: {
+                                          description: 'values is an array of string values. If the operator is In or NotIn,\nthe values array must be non-empty. If the operator is Exists or DoesNotExist,\nthe values array must be empty. This array is replaced during a strategic\nmerge patch.',
+                                          items: {
+                                            type: 'string',
+                                          },
+                                          type: 'array',
+                                        },
+                                      },
+                                      required: [
+                                        'key',
+                                        'operator',
+                                      ],
+                                      type: 'object',
+                                    },
+                                    type: 'array',
+                                  },
+                                  matchLabels: {
+                                    additionalProperties: {
+                                      type: 'string',
+                                    },
+                                    description: 'matchLabels is a map of {key,value} pairs. A single {key,value} in the matchLabels\nmap is equivalent to an element of matchExpressions, whose key field is "key", the\noperator is "In", and the values array contains only "value". The requirements are ANDed.',
+                                    type: 'object',
+                                  },
+                                },
+                                type: 'object',
+                                'x-kubernetes-map-type': 'atomic',
+                              },
+                            },
+                            type: 'object',
+                          },
+                          providerSelector: {
+                            items: {
+                              properties: {
+                                filter: {
+                                  description: 'Filter selects the part of the cidrs of the provider the rule whitelists, the provider is still fetched once for\nall the rules using it',
+                                  properties: {
+                                    ipFamily: {
+                                      description: 'IPFamily of the cidrs, only IPv4 for Cloudflare and both for the other providers if not set',
+                                      enum: [
+                                        'IPv4',
+                                        'IPv6',
+                                        'DualStack',
+                                      ],
+                                      type: 'string',
+                                    },
+                                    regions: {
+                                      description: 'Regions of the cidrs for the cloud providers, all regions if not set',
+                                      items: {
+                                        type: 'string',
+                                      },
+                                      type: 'array',
+                                    },
+                                    services: {
+                                      description: 'Services of the provider, like hooks or actions of GitHub, all the services fetched by the provider if not set',
+                                      items: {
+                                        type: 'string',
+                                      },
+                                      type: 'array',
+                                    },
+                                  },
+                                  type: 'object',
+                                },
                                 name: {
                                   type: 'string',
                                 },
@@ -361,12 +1035,552 @@
                   ],
                   type: 'object',
                 },
+                status: {
+                  description: 'IPWhitelistConfigStatus defines the observed state of IPWhitelistConfig',
+                  properties: {
+                    conditions: {
+                      items: {
+                        description: 'Condition contains details for one aspect of the current state of this API Resource.',
+                        properties: {
+                          lastTransitionTime: {
+                            description: 'lastTransitionTime is the last time the condition transitioned from one status to another.\nThis should be when the underlying condition changed.  If that is not known, then using the time when the API field changed is acceptable.',
+                            format: 'date-time',
+                            type: 'string',
+                          },
+                          message: {
+                            description: 'message is a human readable message indicating details about the transition.\nThis may be an empty string.',
+                            maxLength: 32768,
+                            type: 'string',
+                          },
+                          observedGeneration: {
+                            description: 'observedGeneration represents the .metadata.generation that the condition was set based upon.\nFor instance, if .metadata.generation is currently 12, but the .status.conditions[x].observedGeneration is 9, the condition is out of date\nwith respect to the current state of the instance.',
+                            format: 'int64',
+                            minimum: 0,
+                            type: 'integer',
+                          },
+                          reason: {
+                            description: "reason contains a programmatic identifier indicating the reason for the condition's last transition.\nProducers of specific condition types may define expected values and meanings for this field,\nand whether the values are considered a guaranteed API.\nThe value should be a CamelCase string.\nThis field may not be empty.",
+                            maxLength: 1024,
+                            minLength: 1,
+                            pattern: '^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$',
+                            type: 'string',
+                          },
+                          status: {
+                            description: 'status of the condition, one of True, False, Unknown.',
+                            enum: [
+                              'True',
+                              'False',
+                              'Unknown',
+                            ],
+                            type: 'string',
+                          },
+                          type: {
+                            description: 'type of condition in CamelCase or in foo.example.com/CamelCase.',
+                            maxLength: 316,
+                            pattern: '^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$',
+                            type: 'string',
+                          },
+                        },
+                        required: [
+                          'lastTransitionTime',
+                          'message',
+                          'reason',
+                          'status',
+                          'type',
+                        ],
+                        type: 'object',
+                      },
+                      type: 'array',
+                      'x-kubernetes-list-map-keys': [
+                        'type',
+                      ],
+                      'x-kubernetes-list-type': 'map',
+                    },
+                    siteShieldProposals: {
+                      description: 'SiteShieldProposals are the proposed cidrs of the Site Shield maps waiting to be acknowledged',
+                      items: {
+                        description: 'SiteShieldProposal is a Site Shield map with proposed cidrs which are not acknowledged yet',
+                        properties: {
+                          acknowledgeRequiredBy: {
+                            description: 'AcknowledgeRequiredBy is the time by which Akamai requires the proposal to be acknowledged',
+                            format: 'date-time',
+                            type: 'string',
+                          },
+                          mapId: {
+                            type: 'integer',
+                          },
+                          proposedCidrs: {
+                            items: {
+                              type: 'string',
+                            },
+                            type: 'array',
+                          },
+                          provider: {
+                            type: 'string',
+                          },
+                        },
+                        required: [
+                          'mapId',
+                          'provider',
+                        ],
+                        type: 'object',
+                      },
+                      type: 'array',
+                      'x-kubernetes-list-map-keys': [
+                        'provider',
+                        'mapId',
+                      ],
+                      'x-kubernetes-list-type': 'map',
+                    },
+                  },
+                  type: 'object',
+                },
+              },
+              type: 'object',
+            },
+          },
+          served: true,
+          storage: true,
+          subresources: {
+            status: {},
+          },
+        },
+      ],
+    },
+  },
+  {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    metadata: {
+      annotations: {
+        'controller-gen.kubebuilder.io/version': 'v0.19.0',
+      },
+      name: 'ipgroups.ingress.security.moulick',
+    },
+    spec: {
+      group: 'ingress.security.moulick',
+      names: {
+        kind: 'IPGroup',
+        listKind: 'IPGroupList',
+        plural: 'ipgroups',
+        singular: 'ipgroup',
+      },
+      scope: 'Cluster',
+      versions: [
+        {
+          name: 'v1beta1',
+          schema: {
+            openAPIV3Schema: {
+              description: 'IPGroup is a group of IPs managed outside of the IPWhitelistConfig, its name is the name of the group',
+              properties: {
+                apiVersion: {
+                  description: 'APIVersion defines the versioned schema of this representation of an object.\nServers should convert recognized schemas to the latest internal value, and\nmay reject unrecognized values.\nMore info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources',
+                  type: 'string',
+                },
+                kind: {
+                  description: 'Kind is a string value representing the REST resource this object represents.\nServers may infer this from the endpoint the client submits requests to.\nCannot be updated.\nIn CamelCase.\nMore info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds',
+                  type: 'string',
+                },
+                metadata: {
+                  type: 'object',
+                },
+                spec: {
+                  description: 'IPGroupSpec is a group of IPs with an optional activation and expiration time',
+                  properties: {
+                    cidrs: {
+                      description: 'CIDRS are either plain strings like "10.0.0.0/8" or objects with their own expiry',
+                      items: {
+                        description: 'CIDR is a single entry of an IPGroup. For compatibility, it can also be given as a plain string, which is the same as\nan entry with only the cidr set. The schema accepts both forms, so the entries are checked when decoded instead: an\nentry with an unknown key or without its cidr is refused, rather than silently losing the misspelled field.',
+                        properties: {
+                          cidr: {
+                            type: 'string',
+                          },
+                          description: {
+                            description: 'Description of who or what the CIDR belongs to',
+                            type: 'string',
+                          },
+                          expires: {
+                            description: 'Expires is the time from which this CIDR is no longer whitelisted, the expiry of the group still applies',
+                            format: 'date-time',
+                            type: 'string',
+                          },
+                          ticket: {
+                            description: 'Ticket is the change request or issue the CIDR was requested in',
+                            type: 'string',
+                          },
+                        },
+                        required: [
+                          'cidr',
+                        ],
+                        'x-kubernetes-preserve-unknown-fields': true,
+                      },
+                      type: 'array',
+                    },
+                    expires: {
+                      description: 'Expires is the time from which the group is no longer whitelisted, the group never expires if not set',
+                      format: 'date-time',
+                      type: 'string',
+                    },
+                    includeGroups: {
+                      description: 'IncludeGroups are the names of other groups whose cidrs are whitelisted along with this group.\nEvery included group still has its own expiry, notBefore and schedule applied.',
+                      items: {
+                        type: 'string',
+                      },
+                      type: 'array',
+                    },
+                    notBefore: {
+                      description: 'NotBefore is the time from which the group is whitelisted, the group is active right away if not set',
+                      format: 'date-time',
+                      type: 'string',
+                    },
+                    schedule: {
+                      description: 'Schedule limits the group to recurring time windows, the group is whitelisted all the time if not set',
+                      properties: {
+                        timeZone: {
+                          default: 'UTC',
+                          description: 'TimeZone is the IANA name of the time zone the windows are in, like "Europe/Berlin"',
+                          type: 'string',
+                        },
+                        windows: {
+                          description: 'Windows during which the group is whitelisted, the group is whitelisted while any of them is open',
+                          items: {
+                            description: 'TimeWindow is a time range repeating on the given days of the week',
+                            properties: {
+                              days: {
+                                description: 'Days of the week on which the window opens, every day if not set',
+                                items: {
+                                  description: 'Weekday is a day of the week',
+                                  enum: [
+                                    'Monday',
+                                    'Tuesday',
+                                    'Wednesday',
+                                    'Thursday',
+                                    'Friday',
+                                    'Saturday',
+                                    'Sunday',
+                                  ],
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              end: {
+                                description: 'End is the time of the day as HH:MM at which the window closes.\nA window ending at or before its start closes on the next day, like 22:00 to 06:00.',
+                                pattern: '^([01][0-9]|2[0-3]):[0-5][0-9]$',
+                                type: 'string',
+                              },
+                              start: {
+                                description: 'Start is the time of the day as HH:MM at which the window opens',
+                                pattern: '^([01][0-9]|2[0-3]):[0-5][0-9]$',
+                                type: 'string',
+                              },
+                            },
+                            required: [
+                              'end',
+                              'start',
+                            ],
+                            type: 'object',
+                          },
+                          minItems: 1,
+                          type: 'array',
+                        },
+                      },
+                      required: [
+                        'windows',
+                      ],
+                      type: 'object',
+                    },
+                  },
+                  type: 'object',
+                },
+              },
+              type: 'object',
+            },
+          },
+          served: true,
+          storage: true,
+        },
+      ],
+    },
+  },
+  {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    metadata: {
+      annotations: {
+        'controller-gen.kubebuilder.io/version': 'v0.19.0',
+      },
+      name: 'namespaceipgroups.ingress.security.moulick',
+    },
+    spec: {
+      group: 'ingress.security.moulick',
+      names: {
+        kind: 'NamespaceIPGroup',
+        listKind: 'NamespaceIPGroupList',
+        plural: 'namespaceipgroups',
+        singular: 'namespaceipgroup',
+      },
+      scope: 'Namespaced',
+      versions: [
+        {
+          name: 'v1beta1',
+          schema: {
+            openAPIV3Schema: {
+              description: 'NamespaceIPGroup is a group of IPs managed by the team owning a namespace. It is only whitelisted for the ingresses\nin its own namespace, and only by rules opting in to it with namespaceIPGroups. Its includeGroups refer to other\nNamespaceIPGroups in the same namespace.',
+              properties: {
+                apiVersion: {
+                  description: 'APIVersion defines the versioned schema of this representation of an object.\nServers should convert recognized schemas to the latest internal value, and\nmay reject unrecognized values.\nMore info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources',
+                  type: 'string',
+                },
+                kind: {
+                  description: 'Kind is a string value representing the REST resource this object represents.\nServers may infer this from the endpoint the client submits requests to.\nCannot be updated.\nIn CamelCase.\nMore info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds',
+                  type: 'string',
+                },
+                metadata: {
+                  type: 'object',
+                },
+                spec: {
+                  description: 'IPGroupSpec is a group of IPs with an optional activation and expiration time',
+                  properties: {
+                    cidrs: {
+                      description: 'CIDRS are either plain strings like "10.0.0.0/8" or objects with their own expiry',
+                      items: {
+                        description: 'CIDR is a single entry of an IPGroup. For compatibility, it can also be given as a plain string, which is the same as\nan entry with only the cidr set. The schema accepts both forms, so the entries are checked when decoded instead: an\nentry with an unknown key or without its cidr is refused, rather than silently losing the misspelled field.',
+                        properties: {
+                          cidr: {
+                            type: 'string',
+                          },
+                          description: {
+                            description: 'Description of who or what the CIDR belongs to',
+                            type: 'string',
+                          },
+                          expires: {
+                            description: 'Expires is the time from which this CIDR is no longer whitelisted, the expiry of the group still applies',
+                            format: 'date-time',
+                            type: 'string',
+                          },
+                          ticket: {
+                            description: 'Ticket is the change request or issue the CIDR was requested in',
+                            type: 'string',
+                          },
+                        },
+                        required: [
+                          'cidr',
+                        ],
+                        'x-kubernetes-preserve-unknown-fields': true,
+                      },
+                      type: 'array',
+                    },
+                    expires: {
+                      description: 'Expires is the time from which the group is no longer whitelisted, the group never expires if not set',
+                      format: 'date-time',
+                      type: 'string',
+                    },
+                    includeGroups: {
+                      description: 'IncludeGroups are the names of other groups whose cidrs are whitelisted along with this group.\nEvery included group still has its own expiry, notBefore and schedule applied.',
+                      items: {
+                        type: 'string',
+                      },
+                      type: 'array',
+                    },
+                    notBefore: {
+                      description: 'NotBefore is the time from which the group is whitelisted, the group is active right away if not set',
+                      format: 'date-time',
+                      type: 'string',
+                    },
+                    schedule: {
+                      description: 'Schedule limits the group to recurring time windows, the group is whitelisted all the time if not set',
+                      properties: {
+                        timeZone: {
+                          default: 'UTC',
+                          description: 'TimeZone is the IANA name of the time zone the windows are in, like "Europe/Berlin"',
+                          type: 'string',
+                        },
+                        windows: {
+                          description: 'Windows during which the group is whitelisted, the group is whitelisted while any of them is open',
+                          items: {
+                            description: 'TimeWindow is a time range repeating on the given days of the week',
+                            properties: {
+                              days: {
+                                description: 'Days of the week on which the window opens, every day if not set',
+                                items: {
+                                  description: 'Weekday is a day of the week',
+                                  enum: [
+                                    'Monday',
+                                    'Tuesday',
+                                    'Wednesday',
+                                    'Thursday',
+                                    'Friday',
+                                    'Saturday',
+                                    'Sunday',
+                                  ],
+                                  type: 'string',
+                                },
+                                type: 'array',
+                              },
+                              end: {
+                                description: 'End is the time of the day as HH:MM at which the window closes.\nA window ending at or before its start closes on the next day, like 22:00 to 06:00.',
+                                pattern: '^([01][0-9]|2[0-3]):[0-5][0-9]$',
+                                type: 'string',
+                              },
+                              start: {
+                                description: 'Start is the time of the day as HH:MM at which the window opens',
+                                pattern: '^([01][0-9]|2[0-3]):[0-5][0-9]$',
+                                type: 'string',
+                              },
+                            },
+                            required: [
+                              'end',
+                              'start',
+                            ],
+                            type: 'object',
+                          },
+                          minItems: 1,
+                          type: 'array',
+                        },
+                      },
+                      required: [
+                        'windows',
+                      ],
+                      type: 'object',
+                    },
+                  },
+                  type: 'object',
+                },
+              },
+              type: 'object',
+            },
+          },
+          served: true,
+          storage: true,
+        },
+      ],
+    },
+  },
+  {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    metadata: {
+      annotations: {
+        'controller-gen.kubebuilder.io/version': 'v0.19.0',
+      },
+      name: 'providersnapshots.ingress.security.moulick',
+    },
+    spec: {
+      group: 'ingress.security.moulick',
+      names: {
+        kind: 'ProviderSnapshot',
+        listKind: 'ProviderSnapshotList',
+        plural: 'providersnapshots',
+        singular: 'providersnapshot',
+      },
+      scope: 'Cluster',
+      versions: [
+        {
+          additionalPrinterColumns: [
+            {
+              jsonPath: '.spec.type',
+              name: 'Type',
+              type: 'string',
+            },
+            {
+              jsonPath: '.spec.source',
+              name: 'Source',
+              type: 'string',
+            },
+            {
+              jsonPath: '.spec.fetchedAt',
+              name: 'Fetched',
+              type: 'date',
+            },
+          ],
+          name: 'v1beta1',
+          schema: {
+            openAPIV3Schema: {
+              description: 'ProviderSnapshot is the last known good list of cidrs of the provider with the same name. The operator writes it on\nevery successful fetch, and it can be written by hand for providers in the snapshotOnly mode.',
+              properties: {
+                apiVersion: {
+                  description: 'APIVersion defines the versioned schema of this representation of an object.\nServers should convert recognized schemas to the latest internal value, and\nmay reject unrecognized values.\nMore info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources',
+                  type: 'string',
+                },
+                kind: {
+                  description: 'Kind is a string value representing the REST resource this object represents.\nServers may infer this from the endpoint the client submits requests to.\nCannot be updated.\nIn CamelCase.\nMore info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds',
+                  type: 'string',
+                },
+                metadata: {
+                  type: 'object',
+                },
+                spec: {
+                  description: 'ProviderSnapshotSpec is the list of cidrs of a provider at some point in time',
+                  properties: {
+                    cidrs: {
+                      items: {
+                        type: 'string',
+                      },
+                      type: 'array',
+                    },
+                    fetchedAt: {
+                      description: 'FetchedAt is the time the cidrs were fetched',
+                      format: 'date-time',
+                      type: 'string',
+                    },
+                    hash: {
+                      description: 'Hash is the sha256 of the sorted cidrs, one per line, followed by a line "service <name> <cidrs>" for every\nservice and "region <name> <cidrs>" for every region, sorted by name with their cidrs sorted and joined by commas.\nThe snapshot is refused if it does not match, it is not checked if not set.',
+                      type: 'string',
+                    },
+                    regions: {
+                      additionalProperties: {
+                        items: {
+                          format: 'int32',
+                          type: 'integer',
+                        },
+                        type: 'array',
+                      },
+                      description: 'Regions are the indices into CIDRs of the cidrs of every region of the provider, for the rules filtering the\nprovider by region',
+                      type: 'object',
+                    },
+                    services: {
+                      additionalProperties: {
+                        items: {
+                          format: 'int32',
+                          type: 'integer',
+                        },
+                        type: 'array',
+                      },
+                      description: 'Services are the indices into CIDRs of the cidrs of every service of the provider, for the rules filtering the\nprovider by service',
+                      type: 'object',
+                    },
+                    source: {
+                      description: 'Source the cidrs were fetched from, a snapshot is only used for a provider with the same source',
+                      type: 'string',
+                    },
+                    type: {
+                      enum: [
+                        'akamai',
+                        'aws',
+                        'azure',
+                        'cloudflare',
+                        'fastly',
+                        'gcp',
+                        'github',
+                      ],
+                      type: 'string',
+                    },
+                  },
+                  required: [
+                    'cidrs',
+                    'fetchedAt',
+                    'source',
+                    'type',
+                  ],
+                  type: 'object',
+                },
               },
               type: 'object',
             },
           },
           served: true,
           storage: true,
+          subresources: {},
         },
       ],
     },
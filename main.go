@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
@@ -25,12 +26,21 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	beta1 "github.com/Moulick/ingress-whitelister/api/v1beta1"
 	"github.com/Moulick/ingress-whitelister/controllers"
@@ -56,16 +66,23 @@ func main() {
 	var port int
 	var ipWhitelistConfig string
 	var requeueInterval time.Duration
+	var expiryWarningWindow time.Duration
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var enableWebhooks bool
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	// Deprecated: this port is does not do anything as the operator is not a server
-	flag.IntVar(&port, "port", 9443, "The port for the operator") //nolint:gomnd
+	flag.IntVar(&port, "port", 9443, "The port the admission webhooks are served on") //nolint:gomnd
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&ipWhitelistConfig, "ip-whitelist-config", "", "The name of the IPWhitelistConfig resource")
 	flag.DurationVar(&requeueInterval, "requeue-interval", 1*time.Minute, "The duration until the next untriggered reconciliation run")
+	flag.DurationVar(&expiryWarningWindow, "expiry-warning-window", 7*24*time.Hour, "How long before their expiry IPGroups are reported as expiring")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "The host:port of the OTLP/HTTP collector to export traces to. Tracing is disabled when empty.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Use plain HTTP instead of HTTPS to export traces to the OTLP collector")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the admission webhooks validating the policy, requires a serving certificate")
 
 	opts := zap.Options{
 		Development: true,
@@ -84,13 +101,30 @@ func main() {
 		ipWhitelistConfig = envvar
 	}
 
+	if otlpEndpoint != "" {
+		shutdown, err := setupTracing(otlpEndpoint, otlpInsecure)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			if err := shutdown(context.Background()); err != nil {
+				setupLog.Error(err, "failed to flush traces")
+			}
+		}()
+		setupLog.Info("tracing enabled", "endpoint", otlpEndpoint)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		// Port:                   port,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: port}),
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "460b0067.moulick",
+		// only the few Secrets and ConfigMaps referenced by the providers are read, caching them would need to list and
+		// watch all of them in the cluster
+		Client: client.Options{Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}}}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -98,15 +132,26 @@ func main() {
 	}
 
 	if err = (&controllers.IPWhitelistConfigReconciler{
-		Client:            mgr.GetClient(),
-		Scheme:            mgr.GetScheme(),
-		IPWhitelistConfig: ipWhitelistConfig,
-		RequeueInterval:   requeueInterval,
-		Log:               ctrl.Log.WithName("controllers").WithName("IPWhitelistConfig"),
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		IPWhitelistConfig:   ipWhitelistConfig,
+		RequeueInterval:     requeueInterval,
+		Log:                 ctrl.Log.WithName("controllers").WithName("IPWhitelistConfig"),
+		Recorder:            mgr.GetEventRecorderFor("ingress-whitelister"),
+		ExpiryWarningWindow: expiryWarningWindow,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IPWhitelistConfig")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&controllers.PolicyValidator{
+			Client:            mgr.GetClient(),
+			IPWhitelistConfig: ipWhitelistConfig,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PolicyValidator")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -124,3 +169,31 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// setupTracing registers a global TracerProvider which exports the spans to the given OTLP/HTTP endpoint.
+// The returned function flushes and stops the exporter.
+func setupTracing(endpoint string, insecure bool) (func(context.Context) error, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("ingress-whitelister"),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp.Shutdown, nil
+}
@@ -3,40 +3,17 @@ package utils
 import (
 	"math/rand"
 	"slices"
-
-	jsoniter "github.com/json-iterator/go"
 )
 
-func ConvertFromJSON(jsonString string) (jsoniter.Any, error) {
-	jsonC := jsoniter.Config{
-		EscapeHTML:                    false,
-		MarshalFloatWith6Digits:       true,
-		ObjectFieldMustBeSimpleString: true,
-		SortMapKeys:                   true,
-		ValidateJsonRawMessage:        true,
-	}
-
-	var data jsoniter.Any
-	err := jsonC.Froze().Unmarshal([]byte(jsonString), &data)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
-}
-
-// ArrayInArray returns true if x is a subset of y
-func ArrayInArray(x, y []string) (bool, string) {
-	if len(x) > len(y) {
-		return false, ""
-	}
-
+// ArrayInArray returns true if x is a subset of y, and otherwise the values of x missing from y
+func ArrayInArray(x, y []string) (bool, []string) {
+	var missing []string
 	for _, xVal := range x {
-		present := slices.Contains(y, xVal)
-		if !present {
-			return false, xVal
+		if !slices.Contains(y, xVal) {
+			missing = append(missing, xVal)
 		}
 	}
-	return true, ""
+	return len(missing) == 0, missing
 }
 
 var letterRunes = []rune(`abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890,./;'[]\<>?:"{}|~!@#$%^&*()_+"'`)